@@ -0,0 +1,188 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"strings"
+)
+
+// A node of the accessibility tree of a page.
+type AXNode struct {
+	// Role (e.g. "button", "heading") and accessible name.
+	Role string
+	Name string
+	// If the node is not exposed to assistive technologies.
+	Ignored bool
+	// Other properties by name (e.g. "level", "focusable", "checked").
+	Properties map[string]interface{}
+	Children   []*AXNode
+	// CDP identifier of the DOM node.
+	BackendDOMNodeId int
+}
+
+// Search the first node, depth first starting from n itself, with the given
+// role and name. An empty role or name matches any. nil is returned if no node
+// matches.
+func (n *AXNode) Find(role, name string) *AXNode {
+	if (role == "" || n.Role == role) && (name == "" || n.Name == name) {
+		return n
+	}
+	for _, child := range n.Children {
+		if found := child.Find(role, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+type cdpAXValue struct {
+	Value interface{} `json:"value"`
+}
+
+func (v *cdpAXValue) string() string {
+	if v == nil {
+		return ""
+	}
+	s, _ := v.Value.(string)
+	return s
+}
+
+type cdpAXNode struct {
+	NodeId     string      `json:"nodeId"`
+	Ignored    bool        `json:"ignored"`
+	Role       *cdpAXValue `json:"role"`
+	Name       *cdpAXValue `json:"name"`
+	Properties []struct {
+		Name  string     `json:"name"`
+		Value cdpAXValue `json:"value"`
+	} `json:"properties"`
+	ParentId         string   `json:"parentId"`
+	ChildIds         []string `json:"childIds"`
+	BackendDOMNodeId int      `json:"backendDOMNodeId"`
+}
+
+// Get the accessibility tree of the page, as computed by the browser, with CDP
+// Accessibility.getFullAXTree. The root of the tree is returned, use Find to
+// make assertions on its nodes.
+// Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) AccessibilityTree() (AXNode, error) {
+	var result struct {
+		Nodes []cdpAXNode `json:"nodes"`
+	}
+	if err := s.ExecuteCDP("Accessibility.getFullAXTree", nil, &result); err != nil {
+		return AXNode{}, err
+	}
+	if len(result.Nodes) == 0 {
+		return AXNode{}, errors.New("accessibility tree failed: empty tree")
+	}
+	nodes := make(map[string]*AXNode, len(result.Nodes))
+	for _, n := range result.Nodes {
+		node := &AXNode{
+			Role:             n.Role.string(),
+			Name:             n.Name.string(),
+			Ignored:          n.Ignored,
+			Properties:       map[string]interface{}{},
+			BackendDOMNodeId: n.BackendDOMNodeId,
+		}
+		for _, p := range n.Properties {
+			node.Properties[p.Name] = p.Value.Value
+		}
+		nodes[n.NodeId] = node
+	}
+	var root *AXNode
+	for _, n := range result.Nodes {
+		node := nodes[n.NodeId]
+		for _, id := range n.ChildIds {
+			if child, ok := nodes[id]; ok {
+				node.Children = append(node.Children, child)
+			}
+		}
+		if root == nil && n.ParentId == "" {
+			root = node
+		}
+	}
+	if root == nil {
+		root = nodes[result.Nodes[0].NodeId]
+	}
+	return *root, nil
+}
+
+// UnknownAriaStateError is returned by WebElement.AriaState for a state that
+// is not an ARIA state or property.
+type UnknownAriaStateError struct {
+	State string
+}
+
+func (e *UnknownAriaStateError) Error() string {
+	return "unknown aria state: " + e.State
+}
+
+// ARIA states and properties accepted by AriaState.
+var ariaStates = map[string]bool{
+	"busy": true, "checked": true, "current": true, "disabled": true, "expanded": true,
+	"hidden": true, "invalid": true, "pressed": true, "readonly": true, "required": true,
+	"selected": true, "modal": true, "haspopup": true, "level": true, "valuenow": true,
+}
+
+const ariaStateScript = `var el = arguments[0], state = arguments[1];
+var aria = el.getAttribute("aria-" + state);
+if (aria !== null) return aria;
+var tag = el.tagName.toLowerCase();
+switch (state) {
+case "checked":
+	if (tag === "input" && (el.type === "checkbox" || el.type === "radio")) {
+		return el.indeterminate ? "mixed" : String(el.checked);
+	}
+	break;
+case "selected":
+	if (tag === "option") return String(el.selected);
+	break;
+case "expanded":
+	if (tag === "details") return String(el.open);
+	if (tag === "summary" && el.parentElement && el.parentElement.tagName.toLowerCase() === "details") {
+		return String(el.parentElement.open);
+	}
+	break;
+case "disabled":
+	if ("disabled" in el) return String(el.matches(":disabled"));
+	break;
+case "required":
+	if ("required" in el) return String(el.required);
+	break;
+case "readonly":
+	if ("readOnly" in el) return String(el.readOnly);
+	break;
+case "invalid":
+	if (el.validity) return String(!el.validity.valid);
+	break;
+case "hidden":
+	return String(el.hidden);
+}
+return "";`
+
+// Read the ARIA state (or property) of the element, e.g. "expanded" or
+// "aria-expanded", to assert the state of a widget whether it is custom or
+// native. The value of the aria- attribute is returned if present, otherwise,
+// for native elements, the equivalent property as "true" or "false":
+//
+//	checked    checkboxes and radio buttons ("mixed" if indeterminate)
+//	selected   options of a select
+//	expanded   details, and their summary
+//	disabled   form controls, disabled by themselves or by a fieldset
+//	required   form controls
+//	readonly   inputs and textareas
+//	invalid    form controls failing their constraints
+//	hidden     any element, its hidden property
+//
+// "" is returned if the state doesn't apply to the element. A
+// *UnknownAriaStateError is returned for a state that is not an ARIA state.
+func (e WebElement) AriaState(state string) (string, error) {
+	name := strings.TrimPrefix(strings.ToLower(state), "aria-")
+	if !ariaStates[name] {
+		return "", &UnknownAriaStateError{State: state}
+	}
+	return e.s.ExecuteScriptString(ariaStateScript, []interface{}{e, name})
+}
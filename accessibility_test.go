@@ -0,0 +1,72 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAccessibilityTree(t *testing.T) {
+	value := func(v interface{}) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "value": v}
+	}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Body["cmd"] == "Accessibility.getFullAXTree" {
+			return map[string]interface{}{"nodes": []map[string]interface{}{
+				{"nodeId": "1", "role": value("RootWebArea"), "name": value("Shop"), "childIds": []string{"2", "3"}},
+				{"nodeId": "2", "parentId": "1", "role": value("heading"), "name": value("Cart"),
+					"properties": []map[string]interface{}{{"name": "level", "value": value(2)}}},
+				{"nodeId": "3", "parentId": "1", "role": value("generic"), "ignored": true, "childIds": []string{"4"}},
+				{"nodeId": "4", "parentId": "3", "role": value("button"), "name": value("Checkout"), "backendDOMNodeId": 42},
+			}}
+		}
+		return nil
+	})
+	tree, err := newFakeSession(t, f, "chrome").AccessibilityTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Role != "RootWebArea" || len(tree.Children) != 2 {
+		t.Fatalf("unexpected root: %+v", tree)
+	}
+	button := tree.Find("button", "Checkout")
+	if button == nil || button.BackendDOMNodeId != 42 {
+		t.Fatalf("button not found: %+v", button)
+	}
+	if heading := tree.Find("heading", ""); heading == nil || heading.Properties["level"] != float64(2) {
+		t.Errorf("unexpected heading: %+v", heading)
+	}
+	if tree.Find("link", "") != nil {
+		t.Error("found a missing role")
+	}
+	if _, err := newFakeSession(t, f, "firefox").AccessibilityTree(); !errors.Is(err, ErrUnsupportedCommand) {
+		t.Errorf("expected ErrUnsupportedCommand on firefox, got %v", err)
+	}
+}
+
+func TestAriaState(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			return "true"
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := WebElement{session, "accordion"}
+	if state, err := el.AriaState("aria-Expanded"); err != nil || state != "true" {
+		t.Errorf("got %q, %v", state, err)
+	}
+	if r := f.last(); r.Body["args"].([]interface{})[1] != "expanded" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	var unknown *UnknownAriaStateError
+	if _, err := el.AriaState("open"); !errors.As(err, &unknown) || unknown.State != "open" {
+		t.Errorf("expected an UnknownAriaStateError, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Origin of the coordinates of a pointer move: PointerOriginViewport,
+// PointerOriginPointer or a WebElement (coordinates relative to its center).
+type PointerOrigin string
+
+const (
+	// Coordinates are relative to the top-left corner of the viewport.
+	PointerOriginViewport = PointerOrigin("viewport")
+	// Coordinates are relative to the current pointer position.
+	PointerOriginPointer = PointerOrigin("pointer")
+)
+
+// Actions is a builder of a sequence of mouse actions sent at once with the
+// "actions" command by Perform.
+//
+//	err := session.Actions().PointerMove(elem, 0, 0).PointerDown(LeftButton).PointerUp(LeftButton).Perform()
+type Actions struct {
+	s       Session
+	pointer []params
+}
+
+// Start a new sequence of actions.
+func (s Session) Actions() *Actions {
+	return &Actions{s: s}
+}
+
+// Move the pointer to x, y relative to origin, that is a PointerOrigin or a
+// WebElement.
+func (a *Actions) PointerMove(origin interface{}, x, y int) *Actions {
+	return a.PointerMoveDuration(origin, x, y, 0)
+}
+
+// Like PointerMove, the move lasts for duration.
+func (a *Actions) PointerMoveDuration(origin interface{}, x, y int, duration time.Duration) *Actions {
+	a.pointer = append(a.pointer, params{
+		"type":     "pointerMove",
+		"origin":   origin,
+		"x":        x,
+		"y":        y,
+		"duration": int(duration / time.Millisecond),
+	})
+	return a
+}
+
+// Press button.
+func (a *Actions) PointerDown(button MouseButton) *Actions {
+	a.pointer = append(a.pointer, params{"type": "pointerDown", "button": button})
+	return a
+}
+
+// Release button.
+func (a *Actions) PointerUp(button MouseButton) *Actions {
+	a.pointer = append(a.pointer, params{"type": "pointerUp", "button": button})
+	return a
+}
+
+// Wait for duration before the next action.
+func (a *Actions) Pause(duration time.Duration) *Actions {
+	a.pointer = append(a.pointer, params{"type": "pause", "duration": int(duration / time.Millisecond)})
+	return a
+}
+
+// Send the actions to the browser.
+func (a *Actions) Perform() error {
+	p := params{"actions": []params{{
+		"type":       "pointer",
+		"id":         "mouse",
+		"parameters": params{"pointerType": "mouse"},
+		"actions":    a.pointer,
+	}}}
+	_, _, err := a.s.do(p, "POST", "/session/%s/actions", a.s.Id)
+	return err
+}
+
+// Click at dx, dy from the top-left corner of the element (not its center,
+// as Click does), e.g. on a point of a slider track or a canvas. The offset
+// must be inside the element.
+func (e WebElement) ClickAtOffset(dx, dy int) error {
+	rect, err := e.Rect()
+	if err != nil {
+		return err
+	}
+	if dx < 0 || dy < 0 || float64(dx) >= rect.Width || float64(dy) >= rect.Height {
+		return fmt.Errorf("click at offset failed: offset %d,%d outside element of size %gx%g", dx, dy, rect.Width, rect.Height)
+	}
+	// pointer moves are relative to the center of the element
+	x := int(float64(dx) - rect.Width/2)
+	y := int(float64(dy) - rect.Height/2)
+	return e.s.Actions().PointerMove(e, x, y).PointerDown(LeftButton).PointerUp(LeftButton).Perform()
+}
+
+// Drag the element onto target with the pointer: press the left button on the
+// center of the element, move to the center of target and release. This is
+// what mouse based drag and drop libraries expect, for libraries using the
+// HTML5 drag and drop API use HTML5DragTo.
+func (e WebElement) DragTo(target WebElement) error {
+	return e.s.Actions().
+		PointerMove(e, 0, 0).
+		PointerDown(LeftButton).
+		PointerMoveDuration(target, 0, 0, 100*time.Millisecond).
+		PointerUp(LeftButton).
+		Perform()
+}
+
+// Move the pointer over el, wait up to timeout for the tooltip matching the
+// CSS selector tooltipSelector to be present and displayed, and return its
+// text. Tooltips of component libraries are usually inserted in the page only
+// on hover, so the selector is searched again at each check. On timeout the
+// returned error wraps ErrWaitTimeout.
+func (s Session) HoverAndGetTooltip(el WebElement, tooltipSelector string, timeout time.Duration) (string, error) {
+	err := s.Actions().PointerMove(el, 0, 0).Perform()
+	if errors.Is(err, ErrUnsupportedCommand) {
+		// drivers without the W3C actions
+		err = s.MoveToCenter(el)
+	}
+	if err != nil {
+		return "", err
+	}
+	var text string
+	err = poll(timeout, func() (bool, error) {
+		tooltip, err := s.FindElement(CSS_Selector, tooltipSelector)
+		if isElementGone(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		displayed, err := tooltip.IsDisplayed()
+		if isElementGone(err) || err == nil && !displayed {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		text, err = tooltip.Text()
+		if isElementGone(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err == ErrWaitTimeout {
+		return "", fmt.Errorf("%w: tooltip %q not displayed", ErrWaitTimeout, tooltipSelector)
+	}
+	return text, err
+}
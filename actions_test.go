@@ -0,0 +1,131 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestActionsElementOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		session interface{}
+		origin  string
+	}{
+		{"legacy", map[string]interface{}{"browserName": "chrome"}, `{"ELEMENT":"el-1"}`},
+		{"w3c", map[string]interface{}{
+			"sessionId":    "w3c-session",
+			"capabilities": map[string]interface{}{"browserName": "chrome"},
+		}, `{"element-6066-11e4-a52e-4f735466cecf":"el-1"}`},
+	}
+	for _, test := range tests {
+		f := newFakeServer(t, func(r fakeRequest) interface{} {
+			switch r.Path {
+			case "/session":
+				return test.session
+			case "/session/fake-session/element", "/session/w3c-session/element":
+				return map[string]string{"ELEMENT": "el-1", w3cElementKey: "el-1"}
+			}
+			return nil
+		})
+		session, err := f.core().NewSession(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		elem, err := session.FindElement(ID, "menu")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = session.Actions().PointerMove(elem, 5, -3).PointerDown(LeftButton).PointerUp(LeftButton).Perform()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := f.last()
+		if r.Path != "/session/"+session.Id+"/actions" {
+			t.Fatalf("%s: unexpected request: %+v", test.name, r)
+		}
+		got, err := json.Marshal(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"actions":[{"actions":[` +
+			`{"duration":0,"origin":` + test.origin + `,"type":"pointerMove","x":5,"y":-3},` +
+			`{"button":0,"type":"pointerDown"},` +
+			`{"button":0,"type":"pointerUp"}],` +
+			`"id":"mouse","parameters":{"pointerType":"mouse"},"type":"pointer"}]}`
+		if string(got) != want {
+			t.Errorf("%s: unexpected payload:\n got %s\nwant %s", test.name, got, want)
+		}
+	}
+}
+
+func TestClickAtOffset(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/element/el-1/location":
+			return map[string]interface{}{"x": 100, "y": 50}
+		case "/session/fake-session/element/el-1/size":
+			return map[string]interface{}{"width": 200, "height": 20}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elem := WebElement{session, "el-1"}
+	if err := elem.ClickAtOffset(10, 5); err != nil {
+		t.Fatal(err)
+	}
+	actions := f.last().Body["actions"].([]interface{})[0].(map[string]interface{})["actions"].([]interface{})
+	move := actions[0].(map[string]interface{})
+	if move["x"] != float64(-90) || move["y"] != float64(-5) {
+		t.Errorf("unexpected move: %v", move)
+	}
+	if err := elem.ClickAtOffset(200, 5); err == nil {
+		t.Error("offset outside the element accepted")
+	}
+}
+
+func TestHoverAndGetTooltip(t *testing.T) {
+	var hovered bool
+	var checks int
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/actions":
+			return &CommandError{StatusCode: UnknownCommand, Message: "unknown command"}
+		case "/session/fake-session/moveto":
+			hovered = r.Body["element"] == "button"
+		case "/session/fake-session/element":
+			if !hovered {
+				return &CommandError{StatusCode: NoSuchElement, Message: "no such element"}
+			}
+			return map[string]string{"ELEMENT": "tooltip"}
+		case "/session/fake-session/element/tooltip/displayed":
+			checks++
+			return checks > 1
+		case "/session/fake-session/element/tooltip/text":
+			return "Save the draft"
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	text, err := session.HoverAndGetTooltip(WebElement{session, "button"}, "[role=tooltip]", time.Second)
+	if err != nil || text != "Save the draft" {
+		t.Fatalf("got %q, %v", text, err)
+	}
+	hovered = false
+	if _, err := session.HoverAndGetTooltip(WebElement{session, "other"}, "[role=tooltip]", 50*time.Millisecond); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("expected ErrWaitTimeout, got %v", err)
+	}
+}
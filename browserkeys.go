@@ -0,0 +1,118 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// a key of a combo for BrowserKey, in the terms of CDP Input.dispatchKeyEvent.
+type cdpKey struct {
+	modifiers int
+	key       string
+	code      string
+	keyCode   int
+}
+
+// CDP modifier bits.
+var cdpModifiers = map[string]int{
+	"alt":     1,
+	"ctrl":    2,
+	"control": 2,
+	"meta":    4,
+	"cmd":     4,
+	"shift":   8,
+}
+
+var cdpNamedKeys = map[string]cdpKey{
+	"enter":     {key: "Enter", code: "Enter", keyCode: 13},
+	"tab":       {key: "Tab", code: "Tab", keyCode: 9},
+	"escape":    {key: "Escape", code: "Escape", keyCode: 27},
+	"esc":       {key: "Escape", code: "Escape", keyCode: 27},
+	"backspace": {key: "Backspace", code: "Backspace", keyCode: 8},
+	"delete":    {key: "Delete", code: "Delete", keyCode: 46},
+	"space":     {key: " ", code: "Space", keyCode: 32},
+	"left":      {key: "ArrowLeft", code: "ArrowLeft", keyCode: 37},
+	"up":        {key: "ArrowUp", code: "ArrowUp", keyCode: 38},
+	"right":     {key: "ArrowRight", code: "ArrowRight", keyCode: 39},
+	"down":      {key: "ArrowDown", code: "ArrowDown", keyCode: 40},
+	"home":      {key: "Home", code: "Home", keyCode: 36},
+	"end":       {key: "End", code: "End", keyCode: 35},
+	"pageup":    {key: "PageUp", code: "PageUp", keyCode: 33},
+	"pagedown":  {key: "PageDown", code: "PageDown", keyCode: 34},
+}
+
+// parse a combo like "Ctrl+Shift+T", "F5" or "Alt+Left".
+func parseKeyCombo(combo string) (cdpKey, error) {
+	parts := strings.Split(combo, "+")
+	var k cdpKey
+	for _, m := range parts[:len(parts)-1] {
+		bit, ok := cdpModifiers[strings.ToLower(strings.TrimSpace(m))]
+		if !ok {
+			return cdpKey{}, errors.New("invalid key combo: unknown modifier: " + m)
+		}
+		k.modifiers |= bit
+	}
+	name := strings.TrimSpace(parts[len(parts)-1])
+	lower := strings.ToLower(name)
+	if named, ok := cdpNamedKeys[lower]; ok {
+		named.modifiers = k.modifiers
+		return named, nil
+	}
+	if len(lower) > 1 && lower[0] == 'f' {
+		if n, err := strconv.Atoi(lower[1:]); err == nil && n >= 1 && n <= 12 {
+			k.key, k.code, k.keyCode = "F"+lower[1:], "F"+lower[1:], 111+n
+			return k, nil
+		}
+	}
+	if len(name) == 1 {
+		c := name[0]
+		switch {
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			upper := strings.ToUpper(name)
+			k.key, k.code, k.keyCode = strings.ToLower(name), "Key"+upper, int(upper[0])
+			if k.modifiers&cdpModifiers["shift"] != 0 {
+				k.key = upper
+			}
+			return k, nil
+		case c >= '0' && c <= '9':
+			k.key, k.code, k.keyCode = name, "Digit"+name, int(c)
+			return k, nil
+		}
+	}
+	return cdpKey{}, errors.New("invalid key combo: unknown key: " + name)
+}
+
+// Press and release a key combo, like "Ctrl+T", "F5" or "Alt+Left", with CDP
+// Input.dispatchKeyEvent. Modifiers are Ctrl, Alt, Shift and Meta (or Cmd),
+// keys are letters, digits, F1-F12 and Enter, Tab, Escape, Backspace, Delete,
+// Space, Left, Up, Right, Down, Home, End, PageUp, PageDown.
+//
+// The events are delivered to the page like real key presses, but shortcuts
+// handled by the browser UI or the operating system (new tab, close window...)
+// are mostly not triggered by them: support depends on the shortcut and the
+// Chrome version. Chrome only, ErrUnsupportedCommand is returned for other
+// browsers.
+func (s Session) BrowserKey(combo string) error {
+	k, err := parseKeyCombo(combo)
+	if err != nil {
+		return err
+	}
+	args := map[string]interface{}{
+		"type":                  "rawKeyDown",
+		"modifiers":             k.modifiers,
+		"key":                   k.key,
+		"code":                  k.code,
+		"windowsVirtualKeyCode": k.keyCode,
+		"nativeVirtualKeyCode":  k.keyCode,
+	}
+	if err := s.ExecuteCDP("Input.dispatchKeyEvent", args, nil); err != nil {
+		return err
+	}
+	args["type"] = "keyUp"
+	return s.ExecuteCDP("Input.dispatchKeyEvent", args, nil)
+}
@@ -0,0 +1,37 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"testing"
+)
+
+func TestParseKeyCombo(t *testing.T) {
+	tests := []struct {
+		combo string
+		want  cdpKey
+	}{
+		{"Ctrl+T", cdpKey{2, "t", "KeyT", 84}},
+		{"ctrl+shift+t", cdpKey{10, "T", "KeyT", 84}},
+		{"F5", cdpKey{0, "F5", "F5", 116}},
+		{"Alt+Left", cdpKey{1, "ArrowLeft", "ArrowLeft", 37}},
+		{"Cmd+1", cdpKey{4, "1", "Digit1", 49}},
+	}
+	for _, test := range tests {
+		got, err := parseKeyCombo(test.combo)
+		if err != nil {
+			t.Errorf("%s: %s", test.combo, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.combo, got, test.want)
+		}
+	}
+	for _, combo := range []string{"Hyper+T", "F13", "Ctrl+", "Ctrl+TT"} {
+		if _, err := parseKeyCombo(combo); err == nil {
+			t.Errorf("%s: expected an error", combo)
+		}
+	}
+}
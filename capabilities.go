@@ -0,0 +1,134 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+)
+
+// Get the string value stored under key.
+// The bool result is false if the key is missing or its value is not a string.
+func (c Capabilities) GetString(key string) (string, bool) {
+	v, ok := c[key].(string)
+	return v, ok
+}
+
+// Get the bool value stored under key.
+// The bool result is false if the key is missing or its value is not a bool.
+func (c Capabilities) GetBool(key string) (bool, bool) {
+	v, ok := c[key].(bool)
+	return v, ok
+}
+
+// Get the int value stored under key.
+// Capabilities decoded from a JSON response store numbers as float64, these are
+// converted if they hold an integral value.
+func (c Capabilities) GetInt(key string) (int, bool) {
+	switch v := c[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		if v != float64(int(v)) {
+			return 0, false
+		}
+		return int(v), true
+	}
+	return 0, false
+}
+
+// Get the JSON object stored under key (e.g. "proxy" or vendor specific options).
+// The bool result is false if the key is missing or its value is not an object.
+func (c Capabilities) GetMap(key string) (map[string]interface{}, bool) {
+	switch v := c[key].(type) {
+	case map[string]interface{}:
+		return v, true
+	case Capabilities:
+		return v, true
+	case params:
+		return v, true
+	}
+	return nil, false
+}
+
+// Ask the driver for a WebDriver BiDi connection ("webSocketUrl": true). If the
+// driver supports it, the endpoint is then available with Session.BiDiURL.
+func (c Capabilities) EnableBiDi() {
+	c["webSocketUrl"] = true
+}
+
+// Values of W3CCapabilities.PageLoadStrategy.
+const (
+	PageLoadNormal = "normal"
+	PageLoadEager  = "eager"
+	PageLoadNone   = "none"
+)
+
+// The "timeouts" capability, in milliseconds. Zero values are omitted, the
+// driver defaults apply.
+type Timeouts struct {
+	Script   int `json:"script,omitempty"`
+	PageLoad int `json:"pageLoad,omitempty"`
+	Implicit int `json:"implicit,omitempty"`
+}
+
+// W3CCapabilities are the standard capabilities of the W3C WebDriver protocol
+// as a struct, an alternative to filling Capabilities by hand. Zero values are
+// omitted. Convert with ToCapabilities to pass them to NewSession:
+//
+//	caps := webdriver.W3CCapabilities{
+//		BrowserName:      "chrome",
+//		PageLoadStrategy: webdriver.PageLoadEager,
+//		Timeouts:         &webdriver.Timeouts{PageLoad: 30000},
+//		Extra:            map[string]interface{}{"se:name": "checkout"},
+//	}
+//	session, err := wd.NewSession(caps.ToCapabilities(), nil)
+type W3CCapabilities struct {
+	BrowserName               string    `json:"browserName,omitempty"`
+	BrowserVersion            string    `json:"browserVersion,omitempty"`
+	PlatformName              string    `json:"platformName,omitempty"`
+	AcceptInsecureCerts       bool      `json:"acceptInsecureCerts,omitempty"`
+	PageLoadStrategy          string    `json:"pageLoadStrategy,omitempty"`
+	Proxy                     *Proxy    `json:"proxy,omitempty"`
+	Timeouts                  *Timeouts `json:"timeouts,omitempty"`
+	StrictFileInteractability bool      `json:"strictFileInteractability,omitempty"`
+	UnhandledPromptBehavior   string    `json:"unhandledPromptBehavior,omitempty"`
+	// see Capabilities.EnableBiDi
+	WebSocketUrl bool `json:"webSocketUrl,omitempty"`
+	// vendor specific options
+	ChromeOptions  map[string]interface{} `json:"goog:chromeOptions,omitempty"`
+	FirefoxOptions map[string]interface{} `json:"moz:firefoxOptions,omitempty"`
+	// Other capabilities, e.g. of a vendor or of a selenium grid. These
+	// override the fields above with the same name.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// Convert c to Capabilities.
+func (c W3CCapabilities) ToCapabilities() Capabilities {
+	type fields W3CCapabilities // without MarshalJSON
+	std := fields(c)
+	std.ChromeOptions, std.FirefoxOptions = nil, nil
+	caps := Capabilities{}
+	// without the options maps only strings, bools and numbers are left, that
+	// always encode
+	data, _ := json.Marshal(std)
+	json.Unmarshal(data, &caps)
+	if c.ChromeOptions != nil {
+		caps["goog:chromeOptions"] = c.ChromeOptions
+	}
+	if c.FirefoxOptions != nil {
+		caps["moz:firefoxOptions"] = c.FirefoxOptions
+	}
+	for k, v := range c.Extra {
+		caps[k] = v
+	}
+	return caps
+}
+
+// Encode c as the JSON object of ToCapabilities, Extra included.
+func (c W3CCapabilities) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ToCapabilities())
+}
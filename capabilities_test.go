@@ -0,0 +1,160 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCapabilitiesGetters(t *testing.T) {
+	var c Capabilities
+	data := `{"browserName":"chrome","takesScreenshot":true,"pageLoadTimeout":300000,"ratio":1.5,"proxy":{"proxyType":"direct"}}`
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := c.GetString("browserName"); !ok || v != "chrome" {
+		t.Errorf("GetString: got %q, %v", v, ok)
+	}
+	if _, ok := c.GetString("takesScreenshot"); ok {
+		t.Error("GetString should fail on a bool value")
+	}
+	if v, ok := c.GetBool("takesScreenshot"); !ok || !v {
+		t.Errorf("GetBool: got %v, %v", v, ok)
+	}
+	if v, ok := c.GetInt("pageLoadTimeout"); !ok || v != 300000 {
+		t.Errorf("GetInt: got %d, %v", v, ok)
+	}
+	if _, ok := c.GetInt("ratio"); ok {
+		t.Error("GetInt should fail on a non integral number")
+	}
+	if _, ok := c.GetInt("missing"); ok {
+		t.Error("GetInt should fail on a missing key")
+	}
+	if m, ok := c.GetMap("proxy"); !ok || m["proxyType"] != "direct" {
+		t.Errorf("GetMap: got %v, %v", m, ok)
+	}
+	c["local"] = 42
+	if v, ok := c.GetInt("local"); !ok || v != 42 {
+		t.Errorf("GetInt on int value: got %d, %v", v, ok)
+	}
+}
+
+func TestBiDiURL(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		desired, _ := r.Body["desiredCapabilities"].(map[string]interface{})
+		if desired["webSocketUrl"] != true {
+			return map[string]interface{}{"browserName": "fake"}
+		}
+		return map[string]interface{}{"browserName": "fake", "webSocketUrl": "ws://127.0.0.1:9222/session/fake-session"}
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url, ok := session.BiDiURL(); ok {
+		t.Errorf("unexpected BiDi url %q", url)
+	}
+	caps := Capabilities{}
+	caps.EnableBiDi()
+	session, err = f.core().NewSession(caps, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url, ok := session.BiDiURL(); !ok || url != "ws://127.0.0.1:9222/session/fake-session" {
+		t.Errorf("BiDiURL: got %q, %v", url, ok)
+	}
+}
+
+func TestW3CCapabilities(t *testing.T) {
+	caps := W3CCapabilities{
+		BrowserName:      "firefox",
+		PageLoadStrategy: PageLoadEager,
+		Proxy:            &Proxy{ProxyType: ProxyManual, HttpProxy: "proxy:3128", NoProxy: hostList{"localhost"}},
+		Timeouts:         &Timeouts{PageLoad: 30000},
+		FirefoxOptions:   map[string]interface{}{"args": []string{"-headless"}},
+		Extra:            map[string]interface{}{"se:name": "checkout"},
+	}
+	data, err := json.Marshal(caps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"browserName":"firefox","moz:firefoxOptions":{"args":["-headless"]},"pageLoadStrategy":"eager",` +
+		`"proxy":{"httpProxy":"proxy:3128","noProxy":["localhost"],"proxyType":"manual"},"se:name":"checkout","timeouts":{"pageLoad":30000}}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+	if name, _ := caps.ToCapabilities().GetString("browserName"); name != "firefox" {
+		t.Errorf("wrong browserName: %q", name)
+	}
+}
+
+func TestSetUserAgent(t *testing.T) {
+	caps := NewChromeCapabilities()
+	caps.options()["args"] = []string{"--headless", "--user-agent=old"}
+	if err := caps.SetUserAgent(""); err == nil {
+		t.Error("expected an error for an empty user agent")
+	}
+	if err := caps.SetUserAgent("TestBot/1.0"); err != nil {
+		t.Fatal(err)
+	}
+	args := caps.options()["args"].([]interface{})
+	if len(args) != 2 || args[0] != "--headless" || args[1] != "--user-agent=TestBot/1.0" {
+		t.Errorf("unexpected args: %q", args)
+	}
+	d := NewFirefoxDriver("firefox", "webdriver.xpi")
+	if err := d.SetUserAgent("TestBot/1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if d.Prefs["general.useragent.override"] != "TestBot/1.0" {
+		t.Errorf("pref not set: %v", d.Prefs["general.useragent.override"])
+	}
+}
+
+// a Logger recording the messages.
+type recordLogger []string
+
+func (l *recordLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestHeadlessWarning(t *testing.T) {
+	for args, want := range map[string]bool{"": false, "--headless": true, "--headless=new": true, "--headless-x": false} {
+		caps := NewChromeCapabilities()
+		if args != "" {
+			caps.options()["args"] = []interface{}{"--no-sandbox", args}
+		}
+		if caps.IsHeadless() != want {
+			t.Errorf("%q: IsHeadless() = %v", args, !want)
+		}
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("always a display")
+	}
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	var logged recordLogger
+	defer func(l Logger) { WarningLogger = l }(WarningLogger)
+	WarningLogger = &logged
+	d := NewChromeDriver("chromedriver")
+	d.url = newFakeServer(t, nil).URL
+	headless := NewChromeCapabilities()
+	headless.options()["args"] = []string{"--headless"}
+	if _, err := d.NewSession(Capabilities(headless), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != 0 {
+		t.Errorf("warning for a headless session: %q", logged)
+	}
+	if _, err := d.NewSession(Capabilities(NewChromeCapabilities()), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "--headless") {
+		t.Errorf("unexpected warnings: %q", logged)
+	}
+}
@@ -0,0 +1,416 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chrome DevTools Protocol (CDP) commands, sent through the chromedriver
+// "goog/cdp/execute" endpoint. See https://chromedevtools.github.io/devtools-protocol/
+
+// reports if the session is driving Chrome.
+func (s Session) isChrome() bool {
+	name, _ := s.Capabilities.GetString("browserName")
+	return strings.EqualFold(name, "chrome")
+}
+
+// Execute the Chrome DevTools Protocol command cmd (e.g. "Network.getAllCookies")
+// with the given arguments and decode its result into result (can be nil).
+// Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) ExecuteCDP(cmd string, args map[string]interface{}, result interface{}) error {
+	if !s.isChrome() {
+		return ErrUnsupportedCommand
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	p := params{"cmd": cmd, "params": args}
+	_, data, err := s.do(p, "POST", "/session/%s/goog/cdp/execute", s.Id)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// cookie as returned by CDP Network domain.
+type cdpCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"`
+	HttpOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	Session  bool    `json:"session"`
+	SameSite string  `json:"sameSite"`
+}
+
+func (c cdpCookie) cookie() Cookie {
+	cookie := Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		SameSite: c.SameSite,
+	}
+	if !c.Session && c.Expires > 0 {
+		cookie.Expiry = c.Expires
+	}
+	return cookie
+}
+
+// Retrieve the cookies of all domains, HttpOnly ones included, using CDP
+// Network.getAllCookies.
+//
+// Unlike GetCookies, that returns only the cookies visible to the current page
+// (and on some drivers not the HttpOnly ones), this returns every cookie of
+// the browser. Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) GetAllCookiesCDP() ([]Cookie, error) {
+	var result struct {
+		Cookies []cdpCookie `json:"cookies"`
+	}
+	if err := s.ExecuteCDP("Network.getAllCookies", nil, &result); err != nil {
+		return nil, err
+	}
+	cookies := make([]Cookie, len(result.Cookies))
+	for i, c := range result.Cookies {
+		cookies[i] = c.cookie()
+	}
+	return cookies, nil
+}
+
+// Set a cookie for any domain, whatever the current page, using CDP
+// Network.setCookie, e.g. to inject the session cookie of a logged in user
+// before the first navigation and skip the login page.
+//
+// The standard SetCookie only sets cookies for the domain of the current page,
+// so it requires navigating to the site first. c.Domain is required, a Path
+// of "" is "/". Chrome only, ErrUnsupportedCommand is returned for other
+// browsers.
+func (s Session) SetCookieForDomain(c Cookie) error {
+	if c.Domain == "" {
+		return errors.New("set cookie failed: domain is required")
+	}
+	if err := validSameSite(c.SameSite); err != nil {
+		return err
+	}
+	args := map[string]interface{}{
+		"name":     c.Name,
+		"value":    c.Value,
+		"domain":   c.Domain,
+		"path":     c.Path,
+		"secure":   c.Secure,
+		"httpOnly": c.HttpOnly,
+	}
+	if c.Path == "" {
+		args["path"] = "/"
+	}
+	if c.SameSite != "" {
+		args["sameSite"] = c.SameSite
+	}
+	if c.Expiry > 0 {
+		args["expires"] = c.Expiry
+	}
+	var result struct {
+		// deprecated, missing in recent versions
+		Success *bool `json:"success"`
+	}
+	if err := s.ExecuteCDP("Network.setCookie", args, &result); err != nil {
+		return err
+	}
+	if result.Success != nil && !*result.Success {
+		return errors.New("set cookie failed: cookie " + c.Name + " rejected by the browser")
+	}
+	return nil
+}
+
+// Reload the current page bypassing the browser cache, e.g. to check that
+// fresh assets are served after a deploy.
+//
+// On Chrome this is CDP Page.reload with ignoreCache, that returns without
+// waiting for the page to load. Other browsers navigate to the current url
+// with an additional "_nocache" query parameter: only the document is fetched
+// again, its subresources can still come from the cache.
+func (s Session) HardReload() error {
+	err := s.ExecuteCDP("Page.reload", map[string]interface{}{"ignoreCache": true}, nil)
+	if !errors.Is(err, ErrUnsupportedCommand) {
+		return err
+	}
+	current, err := s.GetUrl()
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(current)
+	if err != nil {
+		return errors.New("hard reload failed: " + err.Error())
+	}
+	q := u.Query()
+	q.Set("_nocache", strconv.FormatInt(time.Now().UnixNano(), 10))
+	u.RawQuery = q.Encode()
+	return s.Url(u.String())
+}
+
+// States of a permission for SetPermission.
+const (
+	PermissionGranted = "granted"
+	PermissionDenied  = "denied"
+	PermissionPrompt  = "prompt"
+)
+
+// Set the state of the permission name for every origin with CDP
+// Browser.setPermission, so that the native permission prompt, that WebDriver
+// can't dismiss, is not shown. state is PermissionGranted, PermissionDenied or
+// PermissionPrompt. Permission names are the ones of the Permissions API, e.g.
+// "geolocation", "notifications", "camera", "microphone", "clipboard-read".
+// Grant "geolocation" and set the position with SetGeoLocation to test
+// location features without prompts.
+// Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) SetPermission(name, state string) error {
+	switch state {
+	case PermissionGranted, PermissionDenied, PermissionPrompt:
+	default:
+		return errors.New("set permission failed: invalid state: " + state)
+	}
+	args := map[string]interface{}{
+		"permission": map[string]interface{}{"name": name},
+		"setting":    state,
+	}
+	return s.ExecuteCDP("Browser.setPermission", args, nil)
+}
+
+// CDP events are not available through chromedriver, features needing them
+// open their own WebSocket connection with the DevTools endpoint of the page.
+
+// a message exchanged on a CDP connection: a command, its response or an event.
+type cdpMessage struct {
+	Id     int             `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *cdpError       `json:"error,omitempty"`
+}
+
+type cdpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e cdpError) Error() string {
+	return "cdp error: " + e.Message
+}
+
+// a CDP WebSocket connection with a page target.
+type cdpConn struct {
+	ws *wsConn
+
+	mu       sync.Mutex
+	lastId   int
+	pending  map[int]chan cdpMessage
+	handlers map[string]func(params json.RawMessage)
+	done     chan struct{}
+	err      error
+}
+
+// a DevTools target as listed by the /json/list endpoint.
+type cdpTarget struct {
+	Id                   string `json:"id"`
+	Type                 string `json:"type"`
+	Url                  string `json:"url"`
+	WebSocketDebuggerUrl string `json:"webSocketDebuggerUrl"`
+}
+
+// open a CDP connection with the page of the current window of s.
+// The DevTools address is read from the "goog:chromeOptions" capability
+// returned by chromedriver.
+func (s Session) cdpConnect() (*cdpConn, error) {
+	if !s.isChrome() {
+		return nil, ErrUnsupportedCommand
+	}
+	opts, _ := s.Capabilities.GetMap("goog:chromeOptions")
+	addr, _ := opts["debuggerAddress"].(string)
+	if addr == "" {
+		return nil, errors.New("cdp connect failed: debuggerAddress not found in session capabilities")
+	}
+	response, err := http.Get("http://" + addr + "/json/list")
+	if err != nil {
+		return nil, errors.New("cdp connect failed: " + err.Error())
+	}
+	defer response.Body.Close()
+	var targets []cdpTarget
+	if err := json.NewDecoder(response.Body).Decode(&targets); err != nil {
+		return nil, errors.New("cdp connect failed: " + err.Error())
+	}
+	handle, err := s.WindowHandle()
+	if err != nil {
+		return nil, err
+	}
+	// chromedriver window handles are the target id, older versions add a prefix
+	id := strings.TrimPrefix(handle.id, "CDwindow-")
+	var target *cdpTarget
+	for i, t := range targets {
+		if t.Type != "page" {
+			continue
+		}
+		if strings.EqualFold(t.Id, id) {
+			target = &targets[i]
+			break
+		}
+		if target == nil {
+			target = &targets[i]
+		}
+	}
+	if target == nil || target.WebSocketDebuggerUrl == "" {
+		return nil, errors.New("cdp connect failed: page target not found")
+	}
+	ws, err := dialWebSocket(target.WebSocketDebuggerUrl)
+	if err != nil {
+		return nil, errors.New("cdp connect failed: " + err.Error())
+	}
+	c := &cdpConn{
+		ws:       ws,
+		pending:  map[int]chan cdpMessage{},
+		handlers: map[string]func(json.RawMessage){},
+		done:     make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *cdpConn) readLoop() {
+	for {
+		data, err := c.ws.ReadMessage()
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			close(c.done)
+			return
+		}
+		var m cdpMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			debugprint("cdp: invalid message: " + err.Error())
+			continue
+		}
+		c.mu.Lock()
+		if m.Id != 0 {
+			ch := c.pending[m.Id]
+			delete(c.pending, m.Id)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- m
+			}
+			continue
+		}
+		handler := c.handlers[m.Method]
+		c.mu.Unlock()
+		if handler != nil {
+			handler(m.Params)
+		}
+	}
+}
+
+// register handler for the event method (e.g. "Page.screencastFrame").
+// Handlers run on the reading goroutine: they must not use call, use send.
+func (c *cdpConn) on(method string, handler func(params json.RawMessage)) {
+	c.mu.Lock()
+	c.handlers[method] = handler
+	c.mu.Unlock()
+}
+
+// send a command without waiting for its response.
+func (c *cdpConn) send(method string, args interface{}) error {
+	_, err := c.write(method, args, false)
+	return err
+}
+
+// send a command, wait for its response and decode it into result (can be nil).
+func (c *cdpConn) call(method string, args interface{}, result interface{}) error {
+	ch, err := c.write(method, args, true)
+	if err != nil {
+		return err
+	}
+	select {
+	case m := <-ch:
+		if m.Error != nil {
+			return *m.Error
+		}
+		if result == nil || len(m.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(m.Result, result)
+	case <-c.done:
+		return c.closedErr()
+	}
+}
+
+func (c *cdpConn) write(method string, args interface{}, wait bool) (chan cdpMessage, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.lastId++
+	m := cdpMessage{Id: c.lastId, Method: method, Params: raw}
+	var ch chan cdpMessage
+	if wait {
+		ch = make(chan cdpMessage, 1)
+		c.pending[m.Id] = ch
+	}
+	c.mu.Unlock()
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-c.done:
+		err = c.closedErr()
+	default:
+		err = c.ws.WriteMessage(data)
+	}
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, m.Id)
+		c.mu.Unlock()
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (c *cdpConn) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return errors.New("cdp connection closed: " + c.err.Error())
+}
+
+func (c *cdpConn) Close() error {
+	return c.ws.Close()
+}
+
+// Set the locale seen by the page (Intl, date and number formatting) using CDP
+// Emulation.setLocaleOverride, "" restores the default.
+//
+// This is best-effort: browsers have no notion of keyboard layout reachable
+// from WebDriver, key strokes sent by SendKeys are not translated by the
+// locale. Use SendKeysIME to exercise composition based input. Chrome only,
+// ErrUnsupportedCommand is returned for other browsers.
+func (s Session) SetKeyboardLocale(locale string) error {
+	return s.ExecuteCDP("Emulation.setLocaleOverride", params{"locale": locale}, nil)
+}
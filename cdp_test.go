@@ -0,0 +1,279 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a session on f for the browser browserName.
+func newFakeSession(t *testing.T, f *fakeServer, browserName string) *Session {
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Capabilities = Capabilities{"browserName": browserName}
+	return session
+}
+
+func TestGetAllCookiesCDP(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/goog/cdp/execute" && r.Body["cmd"] == "Network.getAllCookies" {
+			return map[string]interface{}{"cookies": []map[string]interface{}{
+				{"name": "sid", "value": "abc", "domain": ".example.com", "path": "/", "expires": -1, "httpOnly": true, "secure": true, "session": true},
+				{"name": "pref", "value": "dark", "domain": "other.org", "path": "/app", "expires": 1700000000.5},
+			}}
+		}
+		return nil
+	})
+	cookies, err := newFakeSession(t, f, "chrome").GetAllCookiesCDP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cookie{
+		{Name: "sid", Value: "abc", Path: "/", Domain: ".example.com", Secure: true, HttpOnly: true},
+		{Name: "pref", Value: "dark", Path: "/app", Domain: "other.org", Expiry: 1700000000.5},
+	}
+	if len(cookies) != len(want) {
+		t.Fatalf("got %d cookies, want %d", len(cookies), len(want))
+	}
+	for i := range want {
+		if cookies[i] != want[i] {
+			t.Errorf("cookie %d: got %+v, want %+v", i, cookies[i], want[i])
+		}
+	}
+
+	_, err = newFakeSession(t, f, "firefox").GetAllCookiesCDP()
+	if !errors.Is(err, ErrUnsupportedCommand) {
+		t.Fatalf("expected ErrUnsupportedCommand on firefox, got %v", err)
+	}
+}
+
+// fakeDevTools serves the /json/list endpoint and a CDP WebSocket for the
+// page target "T1". Every command received is passed to handle, that must
+// write the response.
+type fakeDevTools struct {
+	*httptest.Server
+}
+
+func newFakeDevTools(t *testing.T, handle func(c *wsConn, m cdpMessage)) *fakeDevTools {
+	d := &fakeDevTools{}
+	d.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json/list":
+			json.NewEncoder(w).Encode([]cdpTarget{
+				{Id: "BG", Type: "background_page", WebSocketDebuggerUrl: "ws://" + r.Host + "/devtools/page/BG"},
+				{Id: "T1", Type: "page", WebSocketDebuggerUrl: "ws://" + r.Host + "/devtools/page/T1"},
+			})
+		case "/devtools/page/T1":
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+			rw.WriteString("Sec-WebSocket-Accept: " + wsAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+			rw.Flush()
+			c := &wsConn{conn: conn, br: bufio.NewReader(conn)}
+			for {
+				data, err := c.ReadMessage()
+				if err != nil {
+					conn.Close()
+					return
+				}
+				var m cdpMessage
+				if err := json.Unmarshal(data, &m); err != nil {
+					t.Error(err)
+				}
+				handle(c, m)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(d.Close)
+	return d
+}
+
+// write a CDP message on c.
+func writeCDP(t *testing.T, c *wsConn, m cdpMessage) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteMessage(data); err != nil {
+		t.Error(err)
+	}
+}
+
+// a chrome session on f whose DevTools endpoint is d.
+func newFakeCDPSession(t *testing.T, f *fakeServer, d *fakeDevTools) *Session {
+	session := newFakeSession(t, f, "chrome")
+	session.Capabilities["goog:chromeOptions"] = map[string]interface{}{
+		"debuggerAddress": strings.TrimPrefix(d.URL, "http://"),
+	}
+	return session
+}
+
+// a goroutine-safe bytes.Buffer.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHardReload(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/url" && r.Method == "GET" {
+			return "http://example.com/app?x=1#top"
+		}
+		return nil
+	})
+	if err := newFakeSession(t, f, "chrome").HardReload(); err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Body["cmd"] != "Page.reload" || r.Body["params"].(map[string]interface{})["ignoreCache"] != true {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	if err := newFakeSession(t, f, "firefox").HardReload(); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := f.last().Body["url"].(string)
+	if !strings.HasPrefix(u, "http://example.com/app?_nocache=") || !strings.HasSuffix(u, "&x=1#top") {
+		t.Errorf("unexpected url: %q", u)
+	}
+}
+
+func TestSetCookieForDomain(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/goog/cdp/execute" {
+			return map[string]interface{}{"success": true}
+		}
+		return nil
+	})
+	session := newFakeSession(t, f, "chrome")
+	if err := session.SetCookieForDomain(Cookie{Name: "sid", Value: "abc"}); err == nil {
+		t.Error("expected an error without domain")
+	}
+	if err := session.SetCookieForDomain(Cookie{Name: "sid", Value: "abc", Domain: "example.com", HttpOnly: true, SameSite: SameSiteLax}); err != nil {
+		t.Fatal(err)
+	}
+	r := f.last()
+	args := r.Body["params"].(map[string]interface{})
+	if r.Body["cmd"] != "Network.setCookie" || args["domain"] != "example.com" || args["path"] != "/" || args["httpOnly"] != true || args["sameSite"] != "Lax" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	if _, ok := args["expires"]; ok {
+		t.Error("session cookie sent with expires")
+	}
+	if err := newFakeSession(t, f, "firefox").SetCookieForDomain(Cookie{Name: "sid", Domain: "example.com"}); !errors.Is(err, ErrUnsupportedCommand) {
+		t.Errorf("expected ErrUnsupportedCommand, got %v", err)
+	}
+}
+
+func TestSetPermission(t *testing.T) {
+	f := newFakeServer(t, nil)
+	if err := newFakeSession(t, f, "chrome").SetPermission("geolocation", PermissionGranted); err != nil {
+		t.Fatal(err)
+	}
+	r := f.last()
+	args, _ := r.Body["params"].(map[string]interface{})
+	if r.Body["cmd"] != "Browser.setPermission" || args["setting"] != "granted" || args["permission"].(map[string]interface{})["name"] != "geolocation" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	if err := newFakeSession(t, f, "chrome").SetPermission("geolocation", "allow"); err == nil {
+		t.Error("invalid state accepted")
+	}
+	if err := newFakeSession(t, f, "firefox").SetPermission("geolocation", PermissionDenied); !errors.Is(err, ErrUnsupportedCommand) {
+		t.Errorf("expected ErrUnsupportedCommand on firefox, got %v", err)
+	}
+}
+
+func TestScreencast(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/window_handle" {
+			return "CDwindow-T1"
+		}
+		return nil
+	})
+	var mu sync.Mutex
+	var acks []float64
+	d := newFakeDevTools(t, func(c *wsConn, m cdpMessage) {
+		switch m.Method {
+		case "Page.startScreencast":
+			var p map[string]interface{}
+			json.Unmarshal(m.Params, &p)
+			if p["format"] != "jpeg" || p["quality"] != float64(50) {
+				t.Errorf("unexpected startScreencast params: %s", m.Params)
+			}
+			writeCDP(t, c, cdpMessage{Id: m.Id, Result: json.RawMessage("{}")})
+			for i, frame := range []string{"frame1", "frame2"} {
+				raw, _ := json.Marshal(map[string]interface{}{
+					"data":      base64.StdEncoding.EncodeToString([]byte(frame)),
+					"sessionId": i + 1,
+				})
+				writeCDP(t, c, cdpMessage{Method: "Page.screencastFrame", Params: raw})
+			}
+		case "Page.screencastFrameAck":
+			var p map[string]interface{}
+			json.Unmarshal(m.Params, &p)
+			mu.Lock()
+			acks = append(acks, p["sessionId"].(float64))
+			mu.Unlock()
+			writeCDP(t, c, cdpMessage{Id: m.Id, Result: json.RawMessage("{}")})
+		default:
+			writeCDP(t, c, cdpMessage{Id: m.Id, Result: json.RawMessage("{}")})
+		}
+	})
+	session := newFakeCDPSession(t, f, d)
+	var out syncBuffer
+	if err := session.StartScreencast(&out, ScreencastOptions{Quality: 50}); err != nil {
+		t.Fatal(err)
+	}
+	// the screencast is shared by the copies of the session
+	copied := *session
+	if err := copied.StartScreencast(&out, ScreencastOptions{}); err == nil {
+		t.Fatal("expected an error starting a second screencast")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != "frame1frame2" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := copied.StopScreencast(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "frame1frame2" {
+		t.Fatalf("unexpected frames: %q", out.String())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(acks) != 2 || acks[0] != 1 || acks[1] != 2 {
+		t.Fatalf("unexpected frame acks: %v", acks)
+	}
+	if err := session.StopScreencast(); err == nil {
+		t.Fatal("expected an error stopping a stopped screencast")
+	}
+}
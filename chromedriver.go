@@ -8,10 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/exec"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,19 +22,38 @@ type ChromeDriver struct {
 	WebDriverCore
 	//The port that ChromeDriver listens on. Default: 9515
 	Port int
+	// If true Start uses exactly Port and fails if it is 0 or already in use,
+	// instead of picking a free port when Port is 0. Default: false
+	RequirePort bool
 	//The URL path prefix to use for all incoming WebDriver REST requests. Default: ""
 	BaseUrl string
 	//The number of threads to use for handling HTTP requests. Default: 4
 	Threads int
 	//The path to use for the ChromeDriver server log. Default: ./chromedriver.log
 	LogPath string
+	// What Start does with an existing log at LogPath. Default: LogPathAppend
+	LogPathMode LogPathMode
+	// With LogPathAppend, the log at LogPath is rotated if bigger than
+	// MaxLogSize bytes. If 0 the log grows without limit. Default: 0
+	MaxLogSize int64
 	// Log file to dump chromedriver stdout/stderr. If "" send to terminal. Default: ""
 	LogFile string
 	// Start method fails if Chromedriver doesn't start in less than StartTimeout. Default 20s.
 	StartTimeout time.Duration
+	// Stop waits up to StopTimeout for Chromedriver to exit after the interrupt, then
+	// kills it. Default 10s.
+	StopTimeout time.Duration
+	// Working directory of the chromedriver process. If "" the current directory. Default: ""
+	Dir string
+	// Environment of the chromedriver process, as "KEY=value" strings. If nil the
+	// environment of the current process. Default: nil
+	Env []string
+	// Times NewSession is retried when the browser fails transiently to start
+	// (e.g. "chrome not reachable" right after chromedriver started). Default: 2
+	NewSessionRetries int
 
 	path    string
-	cmd     *exec.Cmd
+	proc    *process
 	logFile *os.File
 }
 
@@ -49,6 +69,8 @@ func NewChromeDriver(path string) *ChromeDriver {
 	d.Threads = 4
 	d.LogPath = "chromedriver.log"
 	d.StartTimeout = 20 * time.Second
+	d.StopTimeout = 10 * time.Second
+	d.NewSessionRetries = 2
 	return d
 }
 
@@ -57,7 +79,11 @@ var switchesFormat = "-port=%d -url-base=%s -log-path=%s -http-threads=%d"
 var cmdchan = make(chan error)
 
 func (d *ChromeDriver) Start() error {
-	if d.Port == 0 {
+	if d.RequirePort {
+		if err := checkPortFree("127.0.0.1", d.Port); err != nil {
+			return err
+		}
+	} else if d.Port == 0 {
 		var err error
 		d.Port, err = GetFreePort()
 		if err != nil {
@@ -66,110 +92,255 @@ func (d *ChromeDriver) Start() error {
 	}
 
 	csferr := "chromedriver start failed: "
-	if d.cmd != nil {
+	if d.proc != nil {
 		return errors.New(csferr + "chromedriver already running")
 	}
+	if err := d.VerifyBinary(); err != nil {
+		return errors.New(csferr + err.Error())
+	}
 
 	if d.LogPath != "" {
-		//check if log-path is writable
-		file, err := os.OpenFile(d.LogPath, os.O_WRONLY|os.O_CREATE, 0664)
-		if err != nil {
+		if err := prepareLogPath(d.LogPath, d.LogPathMode, d.MaxLogSize); err != nil {
 			return errors.New(csferr + "unable to write in log path: " + err.Error())
 		}
-		file.Close()
 	}
 
 	d.url = fmt.Sprintf("http://127.0.0.1:%d%s", d.Port, d.BaseUrl)
+	cmd := exec.Command(d.path, d.switches()...)
+	cmd.Dir = d.Dir
+	cmd.Env = d.Env
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	var err error
+	if d.LogFile != "" {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		d.logFile, err = os.OpenFile(d.LogFile, flags, 0640)
+		if err != nil {
+			return err
+		}
+		stdout, stderr = d.logFile, d.logFile
+	}
+	d.proc, err = startProcess(cmd, stdout, stderr)
+	if err == nil {
+		err = d.proc.waitStart(d.Port, d.StartTimeout)
+	}
+	if err != nil {
+		abortStart(d.proc, d.logFile, d.StopTimeout)
+		d.proc, d.logFile = nil, nil
+		return errors.New(csferr + err.Error())
+	}
+	return nil
+}
+
+// the command line switches of chromedriver.
+func (d *ChromeDriver) switches() []string {
 	var switches []string
 	switches = append(switches, "-port="+strconv.Itoa(d.Port))
 	switches = append(switches, "-log-path="+d.LogPath)
+	// chromedriver truncates the log at log-path unless asked to append
+	if d.LogPath != "" && d.LogPathMode == LogPathAppend {
+		switches = append(switches, "-append-log")
+	}
 	switches = append(switches, "-http-threads="+strconv.Itoa(d.Threads))
 	if d.BaseUrl != "" {
 		switches = append(switches, "-url-base="+d.BaseUrl)
 	}
+	return switches
+}
 
-	d.cmd = exec.Command(d.path, switches...)
-	stdout, err := d.cmd.StdoutPipe()
-	if err != nil {
-		return errors.New(csferr + err.Error())
+// Create a new session, see WebDriverCore.NewSession. A warning is logged (see
+// WarningLogger) if Chrome is going to be launched without --headless where
+// there is no display, e.g. in CI, since it would then fail to start or hang
+// until a timeout. It's only a warning as some setups provide a virtual
+// display (e.g. Xvfb) that doesn't set DISPLAY for this process.
+func (d *ChromeDriver) NewSession(desired, required Capabilities) (*Session, error) {
+	if !hasDisplay() && !ChromeCapabilities(desired).showsNoWindow() && !ChromeCapabilities(required).showsNoWindow() {
+		warnf("no display found (DISPLAY is not set) and chrome is not headless, add --headless to the chrome args")
 	}
-	stderr, err := d.cmd.StderrPipe()
-	if err != nil {
-		return errors.New(csferr + err.Error())
+	session, err := d.WebDriverCore.NewSession(desired, required)
+	for attempt := 1; attempt <= d.NewSessionRetries && isChromeStartupFlake(err); attempt++ {
+		debugprint("new session failed, retrying: " + err.Error())
+		time.Sleep(time.Duration(attempt) * newSessionBackoff)
+		session, err = d.WebDriverCore.NewSession(desired, required)
 	}
-	if err := d.cmd.Start(); err != nil {
-		return errors.New(csferr + err.Error())
+	return session, err
+}
+
+// Create a session, run fn with it and delete it, see
+// WebDriverCore.WithSession. The session is created by ChromeDriver.NewSession,
+// with its retries.
+func (d *ChromeDriver) WithSession(caps Capabilities, fn func(*Session) error) error {
+	return withSession(d, caps, fn)
+}
+
+// pause before the first retry of NewSession, the n-th retry waits n times as
+// long.
+var newSessionBackoff = 500 * time.Millisecond
+
+// reports if err is a transient failure of chrome to start, worth retrying.
+// Session not created errors that can't get better (e.g. a chrome version not
+// supported by chromedriver, a missing binary) are not.
+func isChromeStartupFlake(err error) bool {
+	if err == nil {
+		return false
 	}
-	if d.LogFile != "" {
-		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
-		d.logFile, err = os.OpenFile(d.LogFile, flags, 0640)
-		if err != nil {
-			return err
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "chrome not reachable") {
+		return true
+	}
+	if statusCode(err) != SessionNotCreatedException && !strings.Contains(msg, "session not created") {
+		return false
+	}
+	for _, permanent := range []string{"only supports", "cannot find", "no such file", "invalid argument"} {
+		if strings.Contains(msg, permanent) {
+			return false
 		}
-		go func() {
-			if _, err := io.Copy(d.logFile, stdout); err != nil {
-				log.Println(err)
-			}
-		}()
-		go func() {
-			if _, err := io.Copy(d.logFile, stderr); err != nil {
-				log.Println(err)
-			}
-		}()
-	} else {
-		go func() {
-			if _, err := io.Copy(os.Stdout, stdout); err != nil {
-				log.Println(err)
-			}
-		}()
-		go func() {
-			if _, err := io.Copy(os.Stderr, stderr); err != nil {
-				log.Println(err)
-			}
-		}()
 	}
-	if err = probePort(d.Port, d.StartTimeout); err != nil {
+	return true
+}
+
+// reports if windows can be shown: always on windows and macOS, with DISPLAY
+// or WAYLAND_DISPLAY set elsewhere.
+func hasDisplay() bool {
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		return true
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+// Check that the chromedriver binary exists and is executable. A bare name
+// (e.g. "chromedriver") is resolved using PATH. Called by Start.
+func (d *ChromeDriver) VerifyBinary() error {
+	path, err := verifyBinary(d.path, "chromedriver")
+	if err != nil {
 		return err
 	}
+	d.path = path
 	return nil
 }
 
+// Stop chromedriver, if running, and start it again with the same configuration,
+// e.g. to recover from a browser crash. Sessions of the previous run are lost.
+// On failure the driver is left stopped.
+func (d *ChromeDriver) Restart() error {
+	return restartDriver(d, d.proc != nil)
+}
+
 func (d *ChromeDriver) Stop() error {
-	if d.cmd == nil {
+	if d.proc == nil {
 		return errors.New("stop failed: chromedriver not running")
 	}
 	defer func() {
-		d.cmd = nil
+		d.proc = nil
 	}()
-	if err := d.cmd.Process.Signal(os.Interrupt); err != nil {
-		return err
-	}
+	err := stopProcess(d.proc, d.StopTimeout)
 	if d.logFile != nil {
-		if err := d.logFile.Close(); err != nil {
-			return err
+		if cerr := d.logFile.Close(); cerr != nil && err == nil {
+			err = cerr
 		}
 	}
+	if err != nil {
+		return errors.New("stop failed: " + err.Error())
+	}
 	return nil
 }
 
-func (d *ChromeDriver) NewSession(desired, required Capabilities) (*Session, error) {
-	//id, capabs, err := d.newSession(desired, required)
-	//return &Session{id, capabs, d}, err
-	session, err := d.newSession(desired, required)
-	if err != nil {
-		return nil, err
+// ChromeCapabilities are Capabilities with helpers to fill the chrome specific
+// "goog:chromeOptions" capability. Convert with Capabilities(c) to pass them
+// to NewSession.
+type ChromeCapabilities Capabilities
+
+// Create chrome capabilities with browserName set to "chrome".
+func NewChromeCapabilities() ChromeCapabilities {
+	return ChromeCapabilities{"browserName": "chrome"}
+}
+
+// returns the "goog:chromeOptions" object, creating it if needed.
+func (c ChromeCapabilities) options() map[string]interface{} {
+	if opts, ok := Capabilities(c).GetMap("goog:chromeOptions"); ok {
+		return opts
 	}
-	session.wd = d
-	return session, nil
+	opts := map[string]interface{}{}
+	c["goog:chromeOptions"] = opts
+	return opts
 }
 
-func (d *ChromeDriver) Sessions() ([]Session, error) {
-	sessions, err := d.sessions()
-	if err != nil {
-		return nil, err
+// Attach the session to an already running Chrome started with
+// --remote-debugging-port, addr is "host:port" of the debugger.
+//
+// When set chromedriver doesn't launch a fresh browser instance and most of
+// the other chrome options (args, binary, extensions, prefs) are ignored.
+func (c ChromeCapabilities) SetDebuggerAddress(addr string) {
+	c.options()["debuggerAddress"] = addr
+}
+
+// Set the user agent of the browser with the --user-agent switch, replacing a
+// previous one. Unlike an override applied once the session exists, this is
+// in effect from the first request of the browser.
+func (c ChromeCapabilities) SetUserAgent(ua string) error {
+	if strings.TrimSpace(ua) == "" {
+		return errors.New("set user agent failed: empty user agent")
+	}
+	opts := c.options()
+	var args []interface{}
+	switch v := opts["args"].(type) {
+	case []interface{}:
+		args = v
+	case []string:
+		for _, arg := range v {
+			args = append(args, arg)
+		}
+	}
+	kept := args[:0:0]
+	for _, arg := range args {
+		if s, ok := arg.(string); ok && strings.HasPrefix(s, "--user-agent=") {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	opts["args"] = append(kept, "--user-agent="+ua)
+	return nil
+}
+
+// the args of the chrome options.
+func (c ChromeCapabilities) args() []string {
+	opts, ok := Capabilities(c).GetMap("goog:chromeOptions")
+	if !ok {
+		return nil
+	}
+	var args []string
+	switch v := opts["args"].(type) {
+	case []string:
+		args = v
+	case []interface{}:
+		for _, arg := range v {
+			if s, ok := arg.(string); ok {
+				args = append(args, s)
+			}
+		}
+	}
+	return args
+}
+
+// Reports if the headless switch (--headless, --headless=new...) is in the
+// chrome args.
+func (c ChromeCapabilities) IsHeadless() bool {
+	for _, arg := range c.args() {
+		arg = "--" + strings.TrimLeft(arg, "-")
+		if arg == "--headless" || strings.HasPrefix(arg, "--headless=") {
+			return true
+		}
 	}
-	for i := range sessions {
-		sessions[i].wd = d
+	return false
+}
+
+// reports if no window is shown by the session: chrome is headless or, when
+// attaching to a running chrome with a debugger address, not launched at all.
+func (c ChromeCapabilities) showsNoWindow() bool {
+	if c.IsHeadless() {
+		return true
 	}
-	return sessions, nil
+	opts, _ := Capabilities(c).GetMap("goog:chromeOptions")
+	addr, _ := opts["debuggerAddress"].(string)
+	return addr != ""
 }
@@ -6,12 +6,15 @@ package webdriver
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 const (
@@ -70,6 +73,32 @@ var statusCodeStrings = map[int]string{
 	34: "Target provided for a move action is out of bounds.",
 }
 
+// status codes of the error codes of the W3C WebDriver protocol, errors
+// without a matching status are UnknownError.
+var w3cErrorCodes = map[string]int{
+	"invalid session id":        NoSuchDriver,
+	"no such element":           NoSuchElement,
+	"no such frame":             NoSuchFrame,
+	"unknown command":           UnknownCommand,
+	"unknown method":            UnknownCommand,
+	"unsupported operation":     UnknownCommand,
+	"stale element reference":   StaleElementReference,
+	"element not interactable":  ElementNotVisible,
+	"invalid element state":     InvalidElementState,
+	"unknown error":             UnknownError,
+	"javascript error":          JavaScriptError,
+	"timeout":                   Timeout,
+	"no such window":            NoSuchWindow,
+	"invalid cookie domain":     InvalidCookieDomain,
+	"unable to set cookie":      UnableToSetCookie,
+	"unexpected alert open":     UnexpectedAlertOpen,
+	"no such alert":             NoAlertOpenError,
+	"script timeout":            ScriptTimeout,
+	"invalid selector":          InvalidSelector,
+	"session not created":       SessionNotCreatedException,
+	"move target out of bounds": MoveTargetOutOfBounds,
+}
+
 //type StatusErrorCode int
 
 type StackFrame struct {
@@ -86,6 +115,8 @@ type CommandError struct {
 	Screen     string
 	Class      string
 	StackTrace []StackFrame
+	// Label of the session that sent the command, see Session.SetLabel.
+	Label string `json:"-"`
 }
 
 func (e CommandError) Error() string {
@@ -94,6 +125,9 @@ func (e CommandError) Error() string {
 	if m != "" {
 		m += ": "
 	}
+	if e.Label != "" {
+		m = "[" + e.Label + "] " + m
+	}
 	if e.StatusCode == -1 {
 		m += "status code not specified"
 	} else if str, found := statusCodeStrings[e.StatusCode]; found {
@@ -104,6 +138,26 @@ func (e CommandError) Error() string {
 	return m
 }
 
+// ErrNoSuchElement is returned by helpers that look for an element without a
+// dedicated protocol command. A CommandError with status NoSuchElement
+// matches it too, so errors.Is(err, ErrNoSuchElement) works in both cases.
+var ErrNoSuchElement = errors.New("no such element")
+
+// ErrUnsupportedCommand is returned by commands that the browser or driver of
+// the session doesn't support. A CommandError with status UnknownCommand
+// matches it too.
+var ErrUnsupportedCommand = errors.New("unsupported command")
+
+func (e CommandError) Is(target error) bool {
+	switch target {
+	case ErrNoSuchElement:
+		return e.StatusCode == NoSuchElement
+	case ErrUnsupportedCommand:
+		return e.StatusCode == UnknownCommand
+	}
+	return false
+}
+
 //type matching the structure standard JSON object response.
 type jsonResponse struct {
 	RawSessionId json.RawMessage `json:"sessionId"`
@@ -130,6 +184,19 @@ func parseError(c int, jr jsonResponse) error {
 		responseCodeError = "Unknown error"
 	}
 	if jr.Status == 0 {
+		// W3C drivers send no status but an error code in the value
+		var w3c struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(jr.RawValue, &w3c); err == nil && w3c.Error != "" {
+			status, found := w3cErrorCodes[w3c.Error]
+			if !found {
+				status = UnknownError
+				w3c.Message = w3c.Error + ": " + w3c.Message
+			}
+			return &CommandError{StatusCode: status, ErrorType: responseCodeError, Message: w3c.Message}
+		}
 		return &CommandError{StatusCode: -1, ErrorType: responseCodeError}
 	}
 	commandError := &CommandError{StatusCode: jr.Status, ErrorType: responseCodeError}
@@ -151,7 +218,7 @@ func newRequest(method, url string, data []byte) (*http.Request, error) {
 	if err != nil {
 		return nil, err
 	}
-	if method == "POST" {
+	if data != nil {
 		request.Header.Add("Content-Type", "application/json;charset=utf-8")
 	}
 	//TODO add png format for screenshots
@@ -161,56 +228,150 @@ func newRequest(method, url string, data []byte) (*http.Request, error) {
 }
 
 type WebDriverCore struct {
-	url string
+	url    string
+	header http.Header
+	// if nil http.DefaultClient is used
+	client *http.Client
+	metrics func(command string, duration time.Duration, err error)
+}
+
+// Call hook after each command sent to the driver with its duration and error,
+// e.g. to find which commands dominate the time of a test suite. command is
+// the method and the url format with the parameters left as %s (e.g.
+// "POST /session/%s/element"), so that the same command of different sessions
+// or elements is reported under the same name. The duration is the wall-clock
+// time of the whole round trip: network, driver processing and, for element
+// searches, the implicit wait. hook is called from the goroutine sending the
+// command and must be safe for concurrent use if sessions are used
+// concurrently. A nil hook disables the metrics. Set it before sending
+// commands, SetMetricsHook is not safe for concurrent use with them.
+func (w *WebDriverCore) SetMetricsHook(hook func(command string, duration time.Duration, err error)) {
+	w.metrics = hook
+}
+
+// Skip the verification of the certificate of a driver served over HTTPS, e.g.
+// a grid behind a self-signed certificate. This is insecure: anyone between
+// the client and the driver can impersonate it. It only affects the connection
+// with the driver, the browser checks the certificates of the sites it visits
+// unless the acceptInsecureCerts capability is set. Default: false
+func (w *WebDriverCore) SetInsecureSkipVerify(b bool) {
+	if !b {
+		w.client = nil
+		return
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	w.client = &http.Client{Transport: transport}
+}
+
+// Add a header to every request sent to the driver, including the one creating
+// the session (e.g. an API key for a cloud grid or a tracing header). Setting
+// an existing key replaces its value. Content-Type is required by the protocol
+// and can't be overridden. Set headers before sending commands, SetHeader is
+// not safe for concurrent use with them.
+func (w *WebDriverCore) SetHeader(key, value string) {
+	if http.CanonicalHeaderKey(key) == "Content-Type" {
+		return
+	}
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	w.header.Set(key, value)
 }
 
 func (w WebDriverCore) Start() error { return nil }
 func (w WebDriverCore) Stop() error  { return nil }
 
-func (w WebDriverCore) do(params interface{}, method, urlFormat string, urlParams ...interface{}) (string, []byte, error) {
+// send a command to the webdriver, label (if not "") is the label of the
+// session shown in debug output.
+func (w WebDriverCore) do(label string, params interface{}, method, urlFormat string, urlParams ...interface{}) (string, []byte, error) {
 	if method != "GET" && method != "POST" && method != "DELETE" {
 		return "", nil, errors.New("invalid method: " + method)
 	}
 	url := w.url + fmt.Sprintf(urlFormat, urlParams...)
-	return w.doInternal(params, method, url)
+	if w.metrics == nil {
+		return w.doInternal(label, params, method, url)
+	}
+	start := time.Now()
+	sessionId, data, err := w.doInternal(label, params, method, url)
+	w.metrics(method+" "+urlFormat, time.Since(start), err)
+	return sessionId, data, err
+}
+
+// send a command with any method to path (relative to the url of the driver),
+// see Session.Do.
+func (w WebDriverCore) doRaw(ctx context.Context, label string, params interface{}, method, path string) (int, jsonResponse, error) {
+	url := w.url + path
+	if w.metrics == nil {
+		return w.send(ctx, label, params, method, url)
+	}
+	start := time.Now()
+	code, jr, err := w.send(ctx, label, params, method, url)
+	w.metrics(method+" "+path, time.Since(start), err)
+	return code, jr, err
 }
 
 //communicate with the server.
-func (w WebDriverCore) doInternal(params interface{}, method, url string) (string, []byte, error) {
-	debugprint(">> " + method + " " + url)
+func (w WebDriverCore) doInternal(label string, params interface{}, method, url string) (string, []byte, error) {
+	_, jr, err := w.send(context.Background(), label, params, method, url)
+	if err != nil {
+		return "", nil, err
+	}
+	sessionId := string(bytes.Trim(jr.RawSessionId, "{}\""))
+	return sessionId, []byte(jr.RawValue), nil
+}
+
+// send a request and return the HTTP status code and the decoded response.
+// POST requests always have a body, other methods only if params is not nil.
+// label, if not "", prefixes the request in debug output.
+func (w WebDriverCore) send(ctx context.Context, label string, params interface{}, method, url string) (int, jsonResponse, error) {
+	if label != "" {
+		debugprint("[" + label + "] >> " + method + " " + url)
+	} else {
+		debugprint(">> " + method + " " + url)
+	}
 	var jsonParams []byte
 	var err error
-	if method == "POST" {
+	if method == "POST" || params != nil {
 		if params == nil {
 			params = map[string]interface{}{}
 		}
 		jsonParams, err = json.Marshal(params)
 		if err != nil {
-			return "", nil, err
+			return 0, jsonResponse{}, err
 		}
 	}
 	request, err := newRequest(method, url, jsonParams)
 	if err != nil {
-		return "", nil, err
+		return 0, jsonResponse{}, err
 	}
-	response, err := http.DefaultClient.Do(request)
+	request = request.WithContext(ctx)
+	for key, values := range w.header {
+		request.Header[key] = values
+	}
+	client := w.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
 	if err != nil {
-		return "", nil, err
+		return 0, jsonResponse{}, err
 	}
+	defer response.Body.Close()
 	debugprint("StatusCode: " + strconv.Itoa(response.StatusCode))
 	//http.Client doesn't follow POST redirected (/session command)
 	if method == "POST" && isRedirect(response) {
 		debugprint("redirected")
 		url, err := response.Location()
 		if err != nil {
-			return "", nil, err
+			return 0, jsonResponse{}, err
 		}
-		return w.doInternal(nil, "GET", url.String())
+		return w.send(ctx, label, nil, "GET", url.String())
 	}
 
 	buf, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return "", nil, err
+		return response.StatusCode, jsonResponse{}, err
 	}
 	head := string(buf)
 	if len(buf) > 1024 {
@@ -221,21 +382,20 @@ func (w WebDriverCore) doInternal(params interface{}, method, url string) (strin
 	jr := &jsonResponse{}
 	err = json.Unmarshal(buf, jr)
 	if err != nil && response.StatusCode == 200 {
-		return "", nil, errors.New("error: response must be a JSON object")
+		return response.StatusCode, *jr, errors.New("error: response must be a JSON object")
 	}
 	//if err = json.Unmarshal(buf, jr); err != nil {
 	//	return "", nil, errors.New("error: response must be a JSON object: "+err.Error())
 	//}
 	if response.StatusCode >= 400 || jr.Status != 0 {
-		return "", nil, parseError(response.StatusCode, *jr)
+		return response.StatusCode, *jr, parseError(response.StatusCode, *jr)
 	}
-	sessionId := string(bytes.Trim(jr.RawSessionId, "{}\""))
-	return sessionId, []byte(jr.RawValue), nil
+	return response.StatusCode, *jr, nil
 }
 
 //Query the server's status.
 func (w WebDriverCore) Status() (*Status, error) {
-	_, data, err := w.do(nil, "GET", "/status")
+	_, data, err := w.do("", nil, "GET", "/status")
 	if err != nil {
 		return nil, err
 	}
@@ -246,28 +406,59 @@ func (w WebDriverCore) Status() (*Status, error) {
 
 //Create a new session.
 //The server should attempt to create a session that most closely matches the desired and required capabilities. Required capabilities have higher priority than desired capabilities and must be set for the session to be created.
-func (w WebDriverCore) newSession(desired, required Capabilities) (*Session, error) {
+//The returned session sends its commands through w. Drivers embedding WebDriverCore share this method.
+func (w *WebDriverCore) NewSession(desired, required Capabilities) (*Session, error) {
 	if desired == nil {
 		desired = map[string]interface{}{}
 	}
 	p := params{"desiredCapabilities": desired, "requiredCapabilities": required}
-	sessionId, data, err := w.do(p, "POST", "/session")
+	sessionId, data, err := w.do("", p, "POST", "/session")
 	if err != nil {
 		return nil, err
 	}
+	// W3C drivers return the session id and the capabilities in the value
+	var w3c struct {
+		SessionId    string       `json:"sessionId"`
+		Capabilities Capabilities `json:"capabilities"`
+	}
+	if err := json.Unmarshal(data, &w3c); err == nil && w3c.SessionId != "" && w3c.Capabilities != nil {
+		return &Session{Id: w3c.SessionId, Capabilities: w3c.Capabilities, wd: w, state: &sessionState{}, w3c: true}, nil
+	}
 	var capabilities Capabilities
 	err = json.Unmarshal(data, &capabilities)
-	return &Session{Id: sessionId, Capabilities: capabilities}, err
+	return &Session{Id: sessionId, Capabilities: capabilities, wd: w, state: &sessionState{}}, err
 }
 
 //Returns a list of the currently active sessions.
-func (w WebDriverCore) sessions() ([]Session, error) {
-	_, data, err := w.do(nil, "GET", "/sessions")
+func (w *WebDriverCore) Sessions() ([]Session, error) {
+	_, data, err := w.do("", nil, "GET", "/sessions")
 	if err != nil {
 		return nil, err
 	}
 	var sessions []Session
 	err = json.Unmarshal(data, &sessions)
-	return sessions, err
-	//return nil, errors.New("unsupported")
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].wd = w
+		sessions[i].state = &sessionState{}
+	}
+	return sessions, nil
+}
+
+//Returns the active sessions whose capabilities satisfy match, e.g. to find
+//the session using a given user-data-dir among many.
+func (w *WebDriverCore) FindSessions(match func(Capabilities) bool) ([]Session, error) {
+	sessions, err := w.Sessions()
+	if err != nil {
+		return nil, err
+	}
+	var found []Session
+	for _, session := range sessions {
+		if match(session.Capabilities) {
+			found = append(found, session)
+		}
+	}
+	return found, nil
 }
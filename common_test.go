@@ -0,0 +1,607 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a request received by fakeServer.
+type fakeRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// a W3C WebDriver error reply of fakeServer: no status, the error code and
+// message in the value.
+type w3cError struct {
+	HTTPStatus int
+	Code       string
+	Message    string
+}
+
+// fakeServer answers JSON Wire Protocol commands with the value returned by
+// reply (a *CommandError or *w3cError value is sent as an error) and records
+// every request it receives.
+type fakeServer struct {
+	*httptest.Server
+	reply func(r fakeRequest) interface{}
+
+	mu       sync.Mutex
+	requests []fakeRequest
+}
+
+func newFakeServer(t *testing.T, reply func(r fakeRequest) interface{}) *fakeServer {
+	f := &fakeServer{reply: reply}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := fakeRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header}
+		buf, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(buf) > 0 {
+			if err := json.Unmarshal(buf, &req.Body); err != nil {
+				t.Error(err)
+			}
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+		var value interface{}
+		if f.reply != nil {
+			value = f.reply(req)
+		}
+		resp := map[string]interface{}{"sessionId": "fake-session", "status": Success, "value": value}
+		if cerr, ok := value.(*CommandError); ok {
+			resp["status"] = cerr.StatusCode
+			resp["value"] = map[string]interface{}{"message": cerr.Message}
+			w.WriteHeader(500)
+		}
+		if werr, ok := value.(*w3cError); ok {
+			resp = map[string]interface{}{"value": map[string]interface{}{"error": werr.Code, "message": werr.Message, "stacktrace": ""}}
+			w.WriteHeader(werr.HTTPStatus)
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// a WebDriverCore talking with f.
+func (f *fakeServer) core() *WebDriverCore {
+	return &WebDriverCore{url: f.URL}
+}
+
+// the last request received.
+func (f *fakeServer) last() fakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		return fakeRequest{}
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+func TestCoreNewSession(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session":
+			return map[string]interface{}{"browserName": "fake"}
+		case "/sessions":
+			return []map[string]interface{}{{"id": "fake-session"}}
+		}
+		return nil
+	})
+	var wd WebDriver = f.core()
+	session, err := wd.NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Id != "fake-session" {
+		t.Fatalf("wrong session id: %q", session.Id)
+	}
+	if name, _ := session.Capabilities.GetString("browserName"); name != "fake" {
+		t.Fatalf("wrong capabilities: %v", session.Capabilities)
+	}
+	if err := session.Url("http://example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Path != "/session/fake-session/url" || r.Body["url"] != "http://example.com" {
+		t.Fatalf("unexpected request: %+v", r)
+	}
+	sessions, err := wd.Sessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Id != "fake-session" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	if err := sessions[0].Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Method != "DELETE" || r.Path != "/session/fake-session" {
+		t.Fatalf("unexpected request: %+v", r)
+	}
+}
+
+func TestDriversShareNewSession(t *testing.T) {
+	for _, wd := range []WebDriver{
+		NewChromeDriver("chromedriver"),
+		NewFirefoxDriver("firefox", "webdriver.xpi"),
+		NewPhantomJsDriver("phantomjs"),
+	} {
+		f := newFakeServer(t, nil)
+		switch d := wd.(type) {
+		case *ChromeDriver:
+			d.url = f.URL
+		case *FirefoxDriver:
+			d.url = f.URL
+		case *PhantomJsDriver:
+			d.url = f.URL
+		}
+		session, err := wd.NewSession(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := session.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+		if r := f.last(); r.Path != "/session/fake-session/refresh" {
+			t.Fatalf("unexpected request: %+v", r)
+		}
+	}
+}
+
+func TestSessionLabel(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/title" {
+			return &CommandError{StatusCode: NoSuchWindow, Message: "window closed"}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Label() != "fake-ses" {
+		t.Fatalf("wrong default label: %q", session.Label())
+	}
+	session.SetLabel("Alice")
+	_, err = session.Title()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.HasPrefix(err.Error(), "[Alice] ") {
+		t.Fatalf("label missing in error: %s", err)
+	}
+}
+
+func TestIsSelected(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/element/box/selected" {
+			return true
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected, err := WebElement{session, "box"}.IsSelected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Method != "GET" || r.Path != "/session/fake-session/element/box/selected" {
+		t.Fatalf("unexpected request: %+v", r)
+	}
+	if !selected {
+		t.Error("selected element reported as not selected")
+	}
+}
+
+func TestSendKeysMultiByte(t *testing.T) {
+	f := newFakeServer(t, nil)
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"h", "é", "日", EnterKey}
+	check := func(path string) {
+		r := f.last()
+		if r.Path != path {
+			t.Fatalf("unexpected request: %+v", r)
+		}
+		keys, _ := r.Body["value"].([]interface{})
+		if len(keys) != len(want) {
+			t.Fatalf("%s: wrong keys: %q", path, keys)
+		}
+		for i := range want {
+			if keys[i] != want[i] {
+				t.Errorf("%s: wrong keys: %q", path, keys)
+			}
+		}
+	}
+	if err := (WebElement{session, "input"}).SendKeys("hé日" + EnterKey); err != nil {
+		t.Fatal(err)
+	}
+	check("/session/fake-session/element/input/value")
+	if err := session.SendKeysOnActiveElement("hé日" + EnterKey); err != nil {
+		t.Fatal(err)
+	}
+	check("/session/fake-session/keys")
+}
+
+func TestRestoreImplicitWait(t *testing.T) {
+	f := newFakeServer(t, nil)
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	implicitWait := func() interface{} {
+		r := f.last()
+		if r.Path != "/session/fake-session/timeouts/implicit_wait" {
+			t.Fatalf("unexpected request: %+v", r)
+		}
+		return r.Body["ms"]
+	}
+	// the implicit wait is shared by the copies of the session, also when
+	// they aren't addressable
+	copies := map[string]Session{"main": *session}
+	if err := copies["main"].SetTimeoutsImplicitWait(3000); err != nil {
+		t.Fatal(err)
+	}
+	copied := *session
+	if err := copied.NoImplicitWait(); err != nil {
+		t.Fatal(err)
+	}
+	if ms := implicitWait(); ms != float64(0) {
+		t.Fatalf("NoImplicitWait sent %v", ms)
+	}
+	if err := session.RestoreImplicitWait(); err != nil {
+		t.Fatal(err)
+	}
+	if ms := implicitWait(); ms != float64(3000) {
+		t.Fatalf("RestoreImplicitWait sent %v", ms)
+	}
+}
+
+func TestSetHeader(t *testing.T) {
+	f := newFakeServer(t, nil)
+	core := f.core()
+	core.SetHeader("X-Api-Key", "secret")
+	core.SetHeader("content-type", "text/plain")
+	check := func(r fakeRequest) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("%s: header missing", r.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json;charset=utf-8" {
+			t.Errorf("%s: Content-Type overridden: %q", r.Path, ct)
+		}
+	}
+	session, err := core.NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(f.last())
+	if err := session.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	check(f.last())
+}
+
+func TestSlowMo(t *testing.T) {
+	f := newFakeServer(t, nil)
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SlowMo = 100 * time.Millisecond
+	defer func() { SlowMo = 0 }()
+	start := time.Now()
+	if _, err := session.Title(); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) >= SlowMo {
+		t.Error("read command paused")
+	}
+	start = time.Now()
+	if err := session.Url("http://example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < SlowMo {
+		t.Error("navigation not paused")
+	}
+}
+
+func TestMaximizeWindowFallback(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/window/current/maximize":
+			return &CommandError{StatusCode: UnknownCommand, Message: "unknown command"}
+		case "/session/fake-session/window/current/size":
+			return map[string]interface{}{"width": 800, "height": 600}
+		case "/session/fake-session/execute":
+			return map[string]interface{}{"width": 1920, "height": 1080}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.GetCurrentWindowHandle().MaximizeWindow(); err != nil {
+		t.Fatal(err)
+	}
+	r := f.last()
+	if r.Method != "POST" || r.Path != "/session/fake-session/window/current/size" || r.Body["width"] != float64(1920) || r.Body["height"] != float64(1080) {
+		t.Errorf("unexpected request: %+v", r)
+	}
+}
+
+func TestWindowType(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/window/new":
+			return map[string]interface{}{"handle": "w2", "type": r.Body["type"]}
+		case "/session/fake-session/window_handles":
+			return []string{"w1", "w2"}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the types are shared by the copies of the session
+	copied := *session
+	window, err := session.NewWindow(WindowTypeTab)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if window.Id() != "w2" {
+		t.Fatalf("wrong handle: %q", window.Id())
+	}
+	for handle, want := range map[string]string{"w1": "unknown", "w2": "tab"} {
+		if typ, err := copied.WindowType(handle); err != nil || typ != want {
+			t.Errorf("%s: got %q, %v", handle, typ, err)
+		}
+	}
+	if _, err := session.WindowType("closed"); err == nil {
+		t.Error("expected an error for a closed window")
+	}
+}
+
+func TestFindSessions(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/sessions" {
+			return []map[string]interface{}{
+				{"id": "s1", "capabilities": map[string]interface{}{"browserName": "chrome"}},
+				{"id": "s2", "capabilities": map[string]interface{}{"browserName": "firefox"}},
+			}
+		}
+		return nil
+	})
+	sessions, err := f.core().FindSessions(func(c Capabilities) bool {
+		name, _ := c.GetString("browserName")
+		return name == "firefox"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Id != "s2" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	if err := sessions[0].Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Path != "/session/s2/refresh" {
+		t.Fatalf("unexpected request: %+v", r)
+	}
+}
+
+func TestSetInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"sessionId":"tls-session","status":0,"value":{}}`))
+	}))
+	defer server.Close()
+	core := &WebDriverCore{url: server.URL}
+	if _, err := core.NewSession(nil, nil); err == nil {
+		t.Fatal("self-signed certificate accepted")
+	}
+	core.SetInsecureSkipVerify(true)
+	session, err := core.NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Id != "tls-session" {
+		t.Errorf("wrong session id: %q", session.Id)
+	}
+}
+
+func TestScreenshotRetry(t *testing.T) {
+	var shots int
+	dead := false
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path != "/session/fake-session/screenshot" {
+			return nil
+		}
+		shots++
+		if dead {
+			return &CommandError{StatusCode: UnknownError, Message: "session deleted because of page crash"}
+		}
+		if shots < 3 {
+			return &CommandError{StatusCode: UnknownError, Message: "unable to capture screen"}
+		}
+		return "cG5n"
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := session.ScreenshotRetry(3, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "png" || shots != 3 {
+		t.Errorf("got %q after %d attempts", data, shots)
+	}
+	dead, shots = true, 0
+	if _, err := session.ScreenshotRetry(3, time.Millisecond); err == nil || shots != 1 {
+		t.Errorf("fatal error retried: %d attempts, %v", shots, err)
+	}
+}
+
+func TestSwitchToFrameBySelector(t *testing.T) {
+	var stale bool
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/element":
+			if r.Body["value"] == "div.missing" {
+				return &CommandError{StatusCode: NoSuchElement, Message: "no such element"}
+			}
+			return map[string]string{"ELEMENT": r.Body["value"].(string)}
+		case "/session/fake-session/element/div/name":
+			return "div"
+		case "/session/fake-session/element/iframe/name":
+			return "IFRAME"
+		case "/session/fake-session/frame":
+			if !stale {
+				stale = true
+				return &CommandError{StatusCode: StaleElementReference, Message: "stale element reference"}
+			}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.SwitchToFrameBySelector("div.missing"); !errors.Is(err, ErrNoSuchElement) {
+		t.Errorf("expected ErrNoSuchElement, got %v", err)
+	}
+	if err := session.SwitchToFrameBySelector("div"); err == nil || !strings.Contains(err.Error(), "not a frame") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := session.SwitchToFrameBySelector("iframe"); err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Path != "/session/fake-session/frame" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+}
+
+func TestSetMetricsHook(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/element" {
+			return &CommandError{StatusCode: NoSuchElement, Message: "no such element"}
+		}
+		return nil
+	})
+	core := f.core()
+	var commands []string
+	var errs []error
+	core.SetMetricsHook(func(command string, duration time.Duration, err error) {
+		if duration <= 0 {
+			t.Errorf("%s: duration %s", command, duration)
+		}
+		commands = append(commands, command)
+		errs = append(errs, err)
+	})
+	session, err := core.NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.FindElement(ID, "missing")
+	if len(commands) != 2 || commands[0] != "POST /session" || commands[1] != "POST /session/%s/element" {
+		t.Fatalf("unexpected commands: %q", commands)
+	}
+	if errs[0] != nil || !errors.Is(errs[1], ErrNoSuchElement) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestSessionDo(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/appium/device/lock":
+			return map[string]interface{}{"locked": true}
+		case "/session/fake-session/vendor/missing":
+			return &CommandError{StatusCode: UnknownCommand, Message: "unknown command"}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct{ Locked bool }
+	resp, err := session.Do(context.Background(), "DELETE", "/appium/device/lock", map[string]int{"seconds": 5}, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Locked || resp.HTTPStatus != 200 || resp.Status != Success {
+		t.Errorf("unexpected response: %+v, %+v", resp, result)
+	}
+	if r := f.last(); r.Method != "DELETE" || r.Body["seconds"] != float64(5) {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	resp, err = session.Do(context.Background(), "GET", "/vendor/missing", nil, nil)
+	if statusCode(err) != UnknownCommand || resp.HTTPStatus != 500 {
+		t.Errorf("unexpected response: %+v, %v", resp, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := session.Do(ctx, "GET", "/title", nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestW3CErrors(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/element":
+			return &w3cError{404, "no such element", "Unable to locate element: #missing"}
+		case "/session/fake-session/element/gone/name":
+			return &w3cError{404, "stale element reference", "element is not attached to the page document"}
+		case "/session/fake-session/window/current/maximize":
+			return &w3cError{404, "unknown command", "unknown command: maximize"}
+		case "/session/fake-session/url":
+			return &w3cError{400, "insecure certificate", "certificate expired"}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = session.FindElement(CSS_Selector, "#missing")
+	if !errors.Is(err, ErrNoSuchElement) || !strings.Contains(err.Error(), "Unable to locate element: #missing") {
+		t.Errorf("no such element: %v", err)
+	}
+	if _, err := (WebElement{session, "gone"}).Name(); !isElementGone(err) || statusCode(err) != StaleElementReference {
+		t.Errorf("stale element reference: %v", err)
+	}
+	// the unknown command falls back to resizing the window to the screen
+	if err := session.GetCurrentWindowHandle().MaximizeWindow(); err != nil || f.last().Path != "/session/fake-session/window/current/size" {
+		t.Errorf("unknown command: %v, last request %+v", err, f.last())
+	}
+	err = session.Url("https://expired.example.com")
+	if statusCode(err) != UnknownError || !strings.Contains(err.Error(), "insecure certificate: certificate expired") {
+		t.Errorf("unmapped error: %v", err)
+	}
+}
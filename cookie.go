@@ -0,0 +1,76 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"time"
+)
+
+// Values of Cookie.SameSite.
+const (
+	SameSiteStrict = "Strict"
+	SameSiteLax    = "Lax"
+	SameSiteNone   = "None"
+)
+
+// Create a cookie to be filled with the chainable setters and added with
+// Session.AddCookie:
+//
+//	session.AddCookie(webdriver.NewCookie("sid", "abc").WithSecure(true).WithExpiry(time.Now().Add(time.Hour)))
+func NewCookie(name, value string) *Cookie {
+	return &Cookie{Name: name, Value: value}
+}
+
+// Set Expiry, in seconds since the epoch, from t.
+func (c *Cookie) SetExpiry(t time.Time) {
+	c.Expiry = float64(t.Unix())
+}
+
+func (c *Cookie) WithDomain(domain string) *Cookie {
+	c.Domain = domain
+	return c
+}
+
+func (c *Cookie) WithPath(path string) *Cookie {
+	c.Path = path
+	return c
+}
+
+func (c *Cookie) WithSecure(secure bool) *Cookie {
+	c.Secure = secure
+	return c
+}
+
+func (c *Cookie) WithHttpOnly(httpOnly bool) *Cookie {
+	c.HttpOnly = httpOnly
+	return c
+}
+
+// Set SameSite to SameSiteStrict, SameSiteLax or SameSiteNone. Other values
+// are reported as an error when the cookie is added.
+func (c *Cookie) WithSameSite(sameSite string) *Cookie {
+	c.SameSite = sameSite
+	return c
+}
+
+func (c *Cookie) WithExpiry(t time.Time) *Cookie {
+	c.SetExpiry(t)
+	return c
+}
+
+// Set a cookie built with NewCookie, see SetCookie.
+func (s Session) AddCookie(cookie *Cookie) error {
+	return s.SetCookie(*cookie)
+}
+
+// SameSite can be empty (browser default) or one of the SameSite constants.
+func validSameSite(sameSite string) error {
+	switch sameSite {
+	case "", SameSiteStrict, SameSiteLax, SameSiteNone:
+		return nil
+	}
+	return errors.New("set cookie failed: invalid SameSite: " + sameSite)
+}
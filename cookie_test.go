@@ -0,0 +1,45 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAddCookie(t *testing.T) {
+	f := newFakeServer(t, nil)
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiry := time.Unix(1700000000, 0)
+	cookie := NewCookie("sid", "abc").WithDomain("example.com").WithSecure(true).WithSameSite(SameSiteLax).WithExpiry(expiry)
+	if err := session.AddCookie(cookie); err != nil {
+		t.Fatal(err)
+	}
+	sent, _ := f.last().Body["cookie"].(map[string]interface{})
+	if sent["name"] != "sid" || sent["domain"] != "example.com" || sent["secure"] != true || sent["sameSite"] != "Lax" || sent["expiry"] != float64(1700000000) {
+		t.Errorf("unexpected cookie: %v", sent)
+	}
+	if err := session.AddCookie(NewCookie("sid", "abc").WithSameSite("lax")); err == nil {
+		t.Error("invalid SameSite accepted")
+	}
+}
+
+func TestCookieWithoutExpiry(t *testing.T) {
+	b, err := json.Marshal(NewCookie("a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent map[string]interface{}
+	if err := json.Unmarshal(b, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sent["expiry"]; ok {
+		t.Errorf("cookie without expiry sent expiry: %s", b)
+	}
+}
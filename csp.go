@@ -0,0 +1,101 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A Content-Security-Policy violation, from a securitypolicyviolation event.
+type CSPViolation struct {
+	// the directive violated, e.g. "script-src-elem"
+	Directive string `json:"directive"`
+	// the resource blocked, "inline" or "eval" for inline scripts and eval
+	BlockedURI string `json:"blockedURI"`
+	// where the violation happened
+	SourceFile   string `json:"sourceFile"`
+	LineNumber   int    `json:"lineNumber"`
+	ColumnNumber int    `json:"columnNumber"`
+	// the first characters of the inline script or style blocked
+	Sample      string `json:"sample"`
+	DocumentURI string `json:"documentURI"`
+}
+
+func (v CSPViolation) String() string {
+	s := v.Directive + " blocked " + v.BlockedURI
+	if v.SourceFile != "" {
+		s += fmt.Sprintf(" at %s:%d:%d", v.SourceFile, v.LineNumber, v.ColumnNumber)
+	}
+	return s
+}
+
+// installs a document listener collecting the violations of the page in
+// window.__webdriverCSPViolations.
+const cspCaptureScript = `(function() {
+	if (window.__webdriverCSPViolations) return;
+	window.__webdriverCSPViolations = [];
+	document.addEventListener('securitypolicyviolation', function(e) {
+		window.__webdriverCSPViolations.push({
+			directive: e.effectiveDirective || e.violatedDirective,
+			blockedURI: e.blockedURI,
+			sourceFile: e.sourceFile,
+			lineNumber: e.lineNumber,
+			columnNumber: e.columnNumber,
+			sample: e.sample,
+			documentURI: e.documentURI
+		});
+	}, true);
+})();`
+
+// Start collecting the Content-Security-Policy violations, read them with
+// CSPViolations.
+//
+// The violations are collected by a securitypolicyviolation listener that is
+// installed in the current page and, with CDP
+// Page.addScriptToEvaluateOnNewDocument, in every page loaded later before any
+// of its scripts run. Chrome only, ErrUnsupportedCommand is returned for other
+// browsers.
+func (s Session) CaptureCSPViolations() error {
+	err := s.ExecuteCDP("Page.addScriptToEvaluateOnNewDocument", map[string]interface{}{"source": cspCaptureScript}, nil)
+	if err != nil {
+		return err
+	}
+	return s.executeScript(cspCaptureScript, nil, nil)
+}
+
+// Return the Content-Security-Policy violations of the current page since the
+// last call. Each violation is reported only once.
+// CaptureCSPViolations must be called first, violations of pages loaded
+// before are lost.
+func (s Session) CSPViolations() ([]CSPViolation, error) {
+	var violations []CSPViolation
+	err := s.executeScriptTyped(`var v = window.__webdriverCSPViolations;
+		if (!v) return null;
+		window.__webdriverCSPViolations = [];
+		return v;`, nil, "array", &violations)
+	if errors.Is(err, ErrScriptNull) {
+		return nil, errors.New("CSP violations failed: not captured, call CaptureCSPViolations first")
+	}
+	return violations, err
+}
+
+// Return an error listing the Content-Security-Policy violations of the
+// current page since the last call, see CSPViolations.
+func (s Session) AssertNoCSPViolations() error {
+	violations, err := s.CSPViolations()
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = v.String()
+	}
+	return fmt.Errorf("%d CSP violations:\n\t%s", len(violations), strings.Join(lines, "\n\t"))
+}
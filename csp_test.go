@@ -0,0 +1,48 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCSPViolations(t *testing.T) {
+	var installed bool
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/goog/cdp/execute":
+			installed = r.Body["cmd"] == "Page.addScriptToEvaluateOnNewDocument"
+			return map[string]interface{}{"identifier": "1"}
+		case "/session/fake-session/execute":
+			if strings.Contains(r.Body["script"].(string), "addEventListener") {
+				return nil
+			}
+			if !installed {
+				return nil
+			}
+			return []map[string]interface{}{{"directive": "script-src-elem", "blockedURI": "inline", "sourceFile": "http://example.com/", "lineNumber": 3, "columnNumber": 1}}
+		}
+		return nil
+	})
+	session := newFakeSession(t, f, "chrome")
+	if _, err := session.CSPViolations(); err == nil {
+		t.Fatal("expected an error before CaptureCSPViolations")
+	}
+	if err := session.CaptureCSPViolations(); err != nil {
+		t.Fatal(err)
+	}
+	violations, err := session.CSPViolations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || violations[0].Directive != "script-src-elem" || violations[0].BlockedURI != "inline" || violations[0].LineNumber != 3 {
+		t.Fatalf("unexpected violations: %+v", violations)
+	}
+	err = session.AssertNoCSPViolations()
+	if err == nil || !strings.Contains(err.Error(), "script-src-elem blocked inline at http://example.com/:3:1") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
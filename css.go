@@ -0,0 +1,162 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CSSValueError is returned by WebElement.ComputedPixels when the computed
+// value of a property is not a single length in pixels (e.g. "auto", "50%" or
+// the several values of a shorthand property).
+type CSSValueError struct {
+	Property string
+	Value    string
+	// Longhand properties to use instead of a shorthand one, if known.
+	Longhands []string
+}
+
+func (e *CSSValueError) Error() string {
+	if len(e.Longhands) > 0 {
+		return fmt.Sprintf("css property %s: %q is not a single pixel value, use a longhand property: %s", e.Property, e.Value, strings.Join(e.Longhands, ", "))
+	}
+	return fmt.Sprintf("css property %s: %q is not a pixel value", e.Property, e.Value)
+}
+
+var cssLonghands = map[string][]string{
+	"margin":        {"margin-top", "margin-right", "margin-bottom", "margin-left"},
+	"padding":       {"padding-top", "padding-right", "padding-bottom", "padding-left"},
+	"border-width":  {"border-top-width", "border-right-width", "border-bottom-width", "border-left-width"},
+	"border-radius": {"border-top-left-radius", "border-top-right-radius", "border-bottom-right-radius", "border-bottom-left-radius"},
+	"inset":         {"top", "right", "bottom", "left"},
+	"gap":           {"row-gap", "column-gap"},
+}
+
+// Read the computed value of the CSS property prop of the element as a number
+// of pixels, e.g. 12 for a "margin-top" of "12px". Values not in pixels return
+// a *CSSValueError, shorthand properties with several values (e.g. "margin")
+// too, suggesting the longhand properties.
+func (e WebElement) ComputedPixels(prop string) (float64, error) {
+	value, err := e.GetCssProperty(prop)
+	if err != nil {
+		return 0, err
+	}
+	v := strings.TrimSpace(value)
+	if strings.Contains(v, " ") {
+		return 0, &CSSValueError{Property: prop, Value: value, Longhands: cssLonghands[prop]}
+	}
+	if !strings.HasSuffix(v, "px") {
+		return 0, &CSSValueError{Property: prop, Value: value}
+	}
+	px, err := strconv.ParseFloat(strings.TrimSuffix(v, "px"), 64)
+	if err != nil {
+		return 0, &CSSValueError{Property: prop, Value: value}
+	}
+	return px, nil
+}
+
+// Read the text generated by CSS for the ::before or ::after pseudo-element of
+// the element (the computed "content" property), e.g. a checkmark or the
+// asterisk of a required field. pseudo is "::before" or "::after". The
+// quotes are removed and the escape sequences decoded, an empty string is
+// returned when nothing is generated ("none" or "normal"). Other parts of the
+// value, e.g. counter(item), are returned as they are.
+func (e WebElement) PseudoContent(pseudo string) (string, error) {
+	switch pseudo {
+	case "::before", "::after", ":before", ":after":
+	default:
+		return "", fmt.Errorf("pseudo content failed: invalid pseudo-element %q, must be ::before or ::after", pseudo)
+	}
+	content, err := e.s.ExecuteScriptString("return getComputedStyle(arguments[0], arguments[1]).content;", []interface{}{e, pseudo})
+	if err != nil {
+		return "", err
+	}
+	return parseCSSContent(content), nil
+}
+
+// the text of a computed "content" value: strings are unquoted and
+// concatenated, the other tokens kept.
+func parseCSSContent(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "none" || value == "normal" {
+		return ""
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		switch c := value[i]; {
+		case c == '"' || c == '\'':
+			i = readCSSString(value, i, &b)
+		case c == ' ':
+			// separates the tokens
+			i++
+		default:
+			// a function as counter(item, ", ") or a keyword as open-quote
+			depth := 0
+			j := i
+			for ; j < len(value); j++ {
+				if value[j] == '(' {
+					depth++
+				} else if value[j] == ')' {
+					depth--
+				} else if depth == 0 && (value[j] == ' ' || value[j] == '"' || value[j] == '\'') {
+					break
+				}
+			}
+			b.WriteString(value[i:j])
+			i = j
+		}
+	}
+	return b.String()
+}
+
+// decode the CSS string starting with the quote at value[start] into b and
+// return the index following its closing quote.
+func readCSSString(value string, start int, b *strings.Builder) int {
+	quote := value[start]
+	i := start + 1
+	for i < len(value) {
+		c := value[i]
+		switch {
+		case c == quote:
+			return i + 1
+		case c == '\\' && i+1 < len(value):
+			i++
+			if value[i] == '\n' {
+				// escaped newline: line continuation
+				i++
+				continue
+			}
+			j := i
+			for j < len(value) && j-i < 6 && isHexDigit(value[j]) {
+				j++
+			}
+			if j == i {
+				b.WriteByte(value[i])
+				i++
+				continue
+			}
+			r, _ := strconv.ParseUint(value[i:j], 16, 32)
+			if r == 0 || r > 0x10ffff {
+				r = 0xfffd
+			}
+			b.WriteRune(rune(r))
+			if j < len(value) && value[j] == ' ' {
+				// a single whitespace ends the escape
+				j++
+			}
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return i
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -0,0 +1,79 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestComputedPixels(t *testing.T) {
+	css := map[string]string{"margin-top": "12.5px", "width": "auto", "margin": "0px 4px"}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		for prop, v := range css {
+			if r.Path == "/session/fake-session/element/1/css/"+prop {
+				return v
+			}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := WebElement{session, "1"}
+	if px, err := el.ComputedPixels("margin-top"); err != nil || px != 12.5 {
+		t.Errorf("margin-top: %v, %v", px, err)
+	}
+	var cssErr *CSSValueError
+	if _, err := el.ComputedPixels("width"); !errors.As(err, &cssErr) || cssErr.Value != "auto" {
+		t.Errorf("width: unexpected error %v", err)
+	}
+	if _, err := el.ComputedPixels("margin"); !errors.As(err, &cssErr) || !strings.Contains(err.Error(), "margin-top") {
+		t.Errorf("margin: unexpected error %v", err)
+	}
+}
+
+func TestParseCSSContent(t *testing.T) {
+	for value, want := range map[string]string{
+		`none`:                   "",
+		`normal`:                 "",
+		`"*"`:                    "*",
+		`'it\'s'`:                "it's",
+		`"\2713"`:                "✓",
+		`"\2713 done"`:           "✓done",
+		`"\"quoted\""`:           `"quoted"`,
+		`"Chapter " counter(n)`:  "Chapter counter(n)",
+		`counter(n, ". ") "end"`: `counter(n, ". ")end`,
+	} {
+		if got := parseCSSContent(value); got != want {
+			t.Errorf("%s: got %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestPseudoContent(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			return `"\2713"`
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elem := WebElement{session, "1"}
+	if content, err := elem.PseudoContent("::before"); err != nil || content != "✓" {
+		t.Errorf("got %q, %v", content, err)
+	}
+	if r := f.last(); r.Body["args"].([]interface{})[1] != "::before" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	if _, err := elem.PseudoContent("::first-line"); err == nil {
+		t.Error("expected an error for ::first-line")
+	}
+}
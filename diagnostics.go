@@ -0,0 +1,111 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The state of a session collected by Session.Diagnostics.
+type Diagnostics struct {
+	Url        string
+	Source     string
+	Screenshot []byte // PNG
+	BrowserLog []LogEntry
+	// Messages of the SEVERE entries of BrowserLog.
+	JSErrors      []string
+	WindowHandles []string
+	// The parts that could not be collected, by name ("url", "source",
+	// "screenshot", "log", "window_handles").
+	Errors map[string]error
+}
+
+// Collect the current url, page source, screenshot, browser log (consuming it,
+// see AssertNoJSErrors) and window handles, e.g. in the failure handler of a
+// test. Collection is best-effort: the parts that fail are recorded in
+// Diagnostics.Errors and the returned error summarizes them, the others are
+// still filled.
+func (s Session) Diagnostics() (*Diagnostics, error) {
+	d := &Diagnostics{Errors: map[string]error{}}
+	var err error
+	if d.Url, err = s.GetUrl(); err != nil {
+		d.Errors["url"] = err
+	}
+	if d.Source, err = s.Source(); err != nil {
+		d.Errors["source"] = err
+	}
+	if d.Screenshot, err = s.Screenshot(); err != nil {
+		d.Errors["screenshot"] = err
+	}
+	if d.BrowserLog, err = s.Log("browser"); err != nil {
+		d.Errors["log"] = err
+	}
+	for _, entry := range d.BrowserLog {
+		if entry.Level == string(LogSevere) {
+			d.JSErrors = append(d.JSErrors, entry.Message)
+		}
+	}
+	handles, err := s.WindowHandles()
+	if err != nil {
+		d.Errors["window_handles"] = err
+	}
+	for _, h := range handles {
+		d.WindowHandles = append(d.WindowHandles, h.id)
+	}
+	if len(d.Errors) == 0 {
+		return d, nil
+	}
+	return d, errors.New("diagnostics incomplete: " + d.errorList("; "))
+}
+
+// the collection errors sorted by part name, joined with sep.
+func (d *Diagnostics) errorList(sep string) string {
+	var list []string
+	for name, err := range d.Errors {
+		list = append(list, name+": "+err.Error())
+	}
+	sort.Strings(list)
+	return strings.Join(list, sep)
+}
+
+// Write the collected parts as files in dir, creating it if needed: url.txt,
+// source.html, screenshot.png, browser.log, js_errors.txt, window_handles.txt
+// and errors.txt if some parts are missing. Empty parts are skipped.
+func (d *Diagnostics) WriteTo(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("write diagnostics failed: %w", err)
+	}
+	var log strings.Builder
+	for _, entry := range d.BrowserLog {
+		fmt.Fprintf(&log, "%d %s %s\n", entry.TimeStamp, entry.Level, entry.Message)
+	}
+	files := []struct {
+		name string
+		data string
+	}{
+		{"url.txt", d.Url},
+		{"source.html", d.Source},
+		{"screenshot.png", string(d.Screenshot)},
+		{"browser.log", log.String()},
+		{"js_errors.txt", strings.Join(d.JSErrors, "\n")},
+		{"window_handles.txt", strings.Join(d.WindowHandles, "\n")},
+		{"errors.txt", d.errorList("\n")},
+	}
+	for _, f := range files {
+		if f.data == "" {
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, f.name), []byte(f.data), 0644); err != nil {
+			return fmt.Errorf("write diagnostics failed: %w", err)
+		}
+	}
+	return nil
+}
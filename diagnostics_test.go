@@ -0,0 +1,57 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/url":
+			return "http://example.com/"
+		case "/session/fake-session/source":
+			return "<html></html>"
+		case "/session/fake-session/screenshot":
+			return &CommandError{StatusCode: UnknownError, Message: "screenshot failed"}
+		case "/session/fake-session/log":
+			return []map[string]interface{}{{"level": "SEVERE", "message": "boom"}}
+		case "/session/fake-session/window_handles":
+			return []string{"w1", "w2"}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := session.Diagnostics()
+	if err == nil || d.Errors["screenshot"] == nil {
+		t.Fatalf("screenshot failure not reported: %v", err)
+	}
+	if d.Url != "http://example.com/" || d.Source != "<html></html>" || len(d.JSErrors) != 1 || len(d.WindowHandles) != 2 {
+		t.Fatalf("unexpected diagnostics: %+v", d)
+	}
+	dir, err := ioutil.TempDir("", "diagnostics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := d.WriteTo(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"url.txt", "source.html", "browser.log", "js_errors.txt", "window_handles.txt", "errors.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "screenshot.png")); !os.IsNotExist(err) {
+		t.Error("empty screenshot written")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// Values of Download.State.
+const (
+	DownloadInProgress = "inProgress"
+	DownloadCompleted  = "completed"
+	DownloadCanceled   = "canceled"
+)
+
+// A download seen by the download capture, see Session.DownloadState.
+type Download struct {
+	// unique id of the download
+	GUID string
+	Url  string
+	// the name of the file as intended by the page or the server
+	SuggestedFilename string
+	// 0 if the size is unknown
+	TotalBytes    int64
+	ReceivedBytes int64
+	// DownloadInProgress, DownloadCompleted or DownloadCanceled
+	State string
+}
+
+// download events recorded on a CDP connection.
+type downloadCapture struct {
+	conn *cdpConn
+
+	mu        sync.Mutex
+	downloads []*Download
+}
+
+// Start tracking the downloads with CDP Browser.setDownloadBehavior, needed by
+// DownloadState. Files are saved in dir, that must be an absolute path; if dir
+// is "" the download directory of the browser (e.g. set with the
+// "download.default_directory" preference) is used. Downloads started before
+// the capture are not seen.
+// Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) StartDownloadCapture(dir string) error {
+	st, err := s.sharedState()
+	if err != nil {
+		return errors.New("start download capture failed: " + err.Error())
+	}
+	st.cdpMu.Lock()
+	defer st.cdpMu.Unlock()
+	if st.downloads != nil {
+		return errors.New("start download capture failed: capture already running")
+	}
+	conn, err := s.cdpConnect()
+	if err != nil {
+		return err
+	}
+	dc := &downloadCapture{conn: conn}
+	conn.on("Browser.downloadWillBegin", dc.downloadWillBegin)
+	conn.on("Browser.downloadProgress", dc.downloadProgress)
+	args := map[string]interface{}{"behavior": "default", "eventsEnabled": true}
+	if dir != "" {
+		args["behavior"] = "allow"
+		args["downloadPath"] = dir
+	}
+	if err := conn.call("Browser.setDownloadBehavior", args, nil); err != nil {
+		conn.Close()
+		return err
+	}
+	st.downloads = dc
+	return nil
+}
+
+func (dc *downloadCapture) downloadWillBegin(raw json.RawMessage) {
+	var event struct {
+		GUID              string `json:"guid"`
+		Url               string `json:"url"`
+		SuggestedFilename string `json:"suggestedFilename"`
+	}
+	if err := json.Unmarshal(raw, &event); err != nil {
+		debugprint("download capture: " + err.Error())
+		return
+	}
+	dc.mu.Lock()
+	dc.downloads = append(dc.downloads, &Download{
+		GUID:              event.GUID,
+		Url:               event.Url,
+		SuggestedFilename: event.SuggestedFilename,
+		State:             DownloadInProgress,
+	})
+	dc.mu.Unlock()
+}
+
+func (dc *downloadCapture) downloadProgress(raw json.RawMessage) {
+	var event struct {
+		GUID          string  `json:"guid"`
+		TotalBytes    float64 `json:"totalBytes"`
+		ReceivedBytes float64 `json:"receivedBytes"`
+		State         string  `json:"state"`
+	}
+	if err := json.Unmarshal(raw, &event); err != nil {
+		debugprint("download capture: " + err.Error())
+		return
+	}
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	for _, d := range dc.downloads {
+		if d.GUID == event.GUID {
+			d.TotalBytes = int64(event.TotalBytes)
+			d.ReceivedBytes = int64(event.ReceivedBytes)
+			d.State = event.State
+			return
+		}
+	}
+}
+
+// Stop the download capture started with StartDownloadCapture, also through
+// another copy of the session.
+func (s Session) StopDownloadCapture() error {
+	st, err := s.sharedState()
+	if err != nil {
+		return errors.New("stop download capture failed: " + err.Error())
+	}
+	st.cdpMu.Lock()
+	dc := st.downloads
+	st.downloads = nil
+	st.cdpMu.Unlock()
+	if dc == nil {
+		return errors.New("stop download capture failed: capture not running")
+	}
+	return dc.conn.Close()
+}
+
+// Return the downloads seen since StartDownloadCapture, in the order they
+// began, with their progress. Unlike polling the download directory, this
+// tells the intended file name and if a download is complete (browsers write
+// to a temporary file first).
+//
+// WebDriver doesn't expose downloads: this is Chrome only and needs the
+// capture to be started before the downloads begin.
+func (s Session) DownloadState() ([]Download, error) {
+	if !s.isChrome() {
+		return nil, ErrUnsupportedCommand
+	}
+	var dc *downloadCapture
+	if st := s.state; st != nil {
+		st.cdpMu.Lock()
+		dc = st.downloads
+		st.cdpMu.Unlock()
+	}
+	if dc == nil {
+		return nil, errors.New("download state failed: download capture not started")
+	}
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	downloads := make([]Download, len(dc.downloads))
+	for i, d := range dc.downloads {
+		downloads[i] = *d
+	}
+	return downloads, nil
+}
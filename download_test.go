@@ -0,0 +1,80 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDownloadState(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/window_handle" {
+			return "T1"
+		}
+		return nil
+	})
+	var behavior map[string]interface{}
+	d := newFakeDevTools(t, func(c *wsConn, m cdpMessage) {
+		if m.Method == "Browser.setDownloadBehavior" {
+			json.Unmarshal(m.Params, &behavior)
+		}
+		writeCDP(t, c, cdpMessage{Id: m.Id, Result: json.RawMessage("{}")})
+		if m.Method == "Browser.setDownloadBehavior" {
+			for _, event := range []cdpMessage{
+				{Method: "Browser.downloadWillBegin", Params: json.RawMessage(`{"guid":"g1","url":"http://a/report.pdf","suggestedFilename":"report.pdf"}`)},
+				{Method: "Browser.downloadWillBegin", Params: json.RawMessage(`{"guid":"g2","url":"http://a/big.zip","suggestedFilename":"big.zip"}`)},
+				{Method: "Browser.downloadProgress", Params: json.RawMessage(`{"guid":"g2","totalBytes":1000,"receivedBytes":10,"state":"inProgress"}`)},
+				{Method: "Browser.downloadProgress", Params: json.RawMessage(`{"guid":"g1","totalBytes":42,"receivedBytes":42,"state":"completed"}`)},
+			} {
+				writeCDP(t, c, event)
+			}
+		}
+	})
+	session := newFakeCDPSession(t, f, d)
+	if _, err := session.DownloadState(); err == nil {
+		t.Fatal("expected an error before starting the capture")
+	}
+	if err := session.StartDownloadCapture("/tmp/downloads"); err != nil {
+		t.Fatal(err)
+	}
+	if behavior["behavior"] != "allow" || behavior["downloadPath"] != "/tmp/downloads" || behavior["eventsEnabled"] != true {
+		t.Errorf("unexpected download behavior: %v", behavior)
+	}
+	// the capture is shared by the copies of the session
+	copied := *session
+	var downloads []Download
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var err error
+		if downloads, err = copied.DownloadState(); err != nil {
+			t.Fatal(err)
+		}
+		if len(downloads) == 2 && downloads[0].State == DownloadCompleted {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(downloads) != 2 {
+		t.Fatalf("unexpected downloads: %+v", downloads)
+	}
+	if d := downloads[0]; d.SuggestedFilename != "report.pdf" || d.State != DownloadCompleted || d.ReceivedBytes != 42 {
+		t.Errorf("unexpected download: %+v", d)
+	}
+	if d := downloads[1]; d.SuggestedFilename != "big.zip" || d.State != DownloadInProgress || d.TotalBytes != 1000 {
+		t.Errorf("unexpected download: %+v", d)
+	}
+	if err := copied.StopDownloadCapture(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.DownloadState(); err == nil {
+		t.Error("expected an error after stopping the capture")
+	}
+	if _, err := newFakeSession(t, f, "firefox").DownloadState(); !errors.Is(err, ErrUnsupportedCommand) {
+		t.Errorf("expected ErrUnsupportedCommand, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var selectorPrefix = regexp.MustCompile(`^([a-z]+)=`)
+
+// Search for an element with a selector whose prefix sets the strategy:
+//
+//	css=div.menu > a     CSS selector
+//	xpath=//a[@href]     XPath expression
+//	id=submit            ID attribute
+//	name=q               NAME attribute
+//	text=Sign in         element whose own text, with normalized spaces, is "Sign in"
+//	link=Sign in         anchor whose visible text is "Sign in"
+//
+// A selector without prefix is a CSS selector. An unknown prefix is an error.
+func (s Session) Find(selector string) (WebElement, error) {
+	using, value, err := parseSelector(selector)
+	if err != nil {
+		return WebElement{}, err
+	}
+	return s.FindElement(using, value)
+}
+
+// split selector into strategy and value, see Session.Find.
+func parseSelector(selector string) (FindElementStrategy, string, error) {
+	m := selectorPrefix.FindStringSubmatch(selector)
+	if m == nil {
+		return CSS_Selector, selector, nil
+	}
+	value := selector[len(m[0]):]
+	switch m[1] {
+	case "css":
+		return CSS_Selector, value, nil
+	case "xpath":
+		return XPath, value, nil
+	case "id":
+		return ID, value, nil
+	case "name":
+		return Name, value, nil
+	case "text":
+		return XPath, textXPath(value, true), nil
+	case "link":
+		return LinkText, value, nil
+	}
+	return "", "", errors.New("unknown selector prefix: " + m[1] + "=")
+}
+
+// Search for an element by its own visible text, compared with normalized
+// spaces. If exact is false text can be a substring of it. As with
+// testing-library's getByText, text can contain any quote.
+func (s Session) FindByText(text string, exact bool) (WebElement, error) {
+	return s.FindElement(XPath, textXPath(text, exact))
+}
+
+// XPath expression matching elements whose own text is (or contains) text.
+func textXPath(text string, exact bool) string {
+	literal := xpathLiteral(strings.Join(strings.Fields(text), " "))
+	if exact {
+		return "//*[normalize-space(text())=" + literal + "]"
+	}
+	return "//*[contains(normalize-space(text()), " + literal + ")]"
+}
+
+// quote s as an XPath string literal. XPath 1.0 has no escaping, strings with
+// both quote kinds are built with concat().
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	parts := strings.Split(s, `"`)
+	var b strings.Builder
+	b.WriteString("concat(")
+	for i, p := range parts {
+		if i > 0 {
+			b.WriteString(`, '"', `)
+		}
+		b.WriteString(`"` + p + `"`)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// How FindByAttribute compares the value of an attribute.
+type MatchType int
+
+const (
+	Exact      MatchType = iota // [attr="value"]
+	Contains                    // [attr*="value"]
+	StartsWith                  // [attr^="value"]
+	EndsWith                    // [attr$="value"]
+)
+
+// Search for an element whose attribute attr matches value, e.g. an
+// auto-generated id with a stable prefix:
+//
+//	elem, err := session.FindByAttribute("id", "order-", webdriver.StartsWith)
+//
+// attr and value are escaped, they can contain any character. As in CSS, an
+// empty value never matches with Contains, StartsWith and EndsWith.
+func (s Session) FindByAttribute(attr, value string, match MatchType) (WebElement, error) {
+	selector, err := attributeSelector(attr, value, match)
+	if err != nil {
+		return WebElement{}, err
+	}
+	return s.FindElement(CSS_Selector, selector)
+}
+
+// CSS attribute selector for FindByAttribute.
+func attributeSelector(attr, value string, match MatchType) (string, error) {
+	if attr == "" {
+		return "", errors.New("find by attribute failed: empty attribute name")
+	}
+	var op string
+	switch match {
+	case Exact:
+		op = "="
+	case Contains:
+		op = "*="
+	case StartsWith:
+		op = "^="
+	case EndsWith:
+		op = "$="
+	default:
+		return "", fmt.Errorf("find by attribute failed: invalid match type %d", match)
+	}
+	return "[" + cssIdent(attr) + op + cssString(value) + "]", nil
+}
+
+// escape s as a CSS identifier.
+func cssIdent(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '_' || r == '-' && i > 0 || r >= 0x80 ||
+			'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' && i > 0:
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f || '0' <= r && r <= '9':
+			fmt.Fprintf(&b, "\\%x ", r)
+		default:
+			b.WriteString("\\" + string(r))
+		}
+	}
+	return b.String()
+}
+
+// quote s as a CSS string.
+func cssString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteString("\\" + string(r))
+		case r < 0x20 || r == 0x7f:
+			// newlines can't be in a string, not even escaped with a backslash
+			fmt.Fprintf(&b, "\\%x ", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,90 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		using    FindElementStrategy
+		value    string
+	}{
+		{"div.menu > a", CSS_Selector, "div.menu > a"},
+		{`input[name="q"]`, CSS_Selector, `input[name="q"]`},
+		{"css=a[href=x]", CSS_Selector, "a[href=x]"},
+		{"xpath=//a[@id='x']", XPath, "//a[@id='x']"},
+		{"id=submit", ID, "submit"},
+		{"name=q", Name, "q"},
+		{"text=Sign in", XPath, `//*[normalize-space(text())="Sign in"]`},
+		{"link=Home", LinkText, "Home"},
+	}
+	for _, test := range tests {
+		using, value, err := parseSelector(test.selector)
+		if err != nil {
+			t.Errorf("%q: %s", test.selector, err)
+			continue
+		}
+		if using != test.using || value != test.value {
+			t.Errorf("%q: got %s %q, want %s %q", test.selector, using, value, test.using, test.value)
+		}
+	}
+	if _, _, err := parseSelector("foo=bar"); err == nil {
+		t.Error("expected an error for an unknown prefix")
+	}
+}
+
+func TestXpathLiteral(t *testing.T) {
+	tests := map[string]string{
+		`plain`:         `"plain"`,
+		`it's`:          `"it's"`,
+		`say "hi"`:      `'say "hi"'`,
+		`it's "quoted"`: `concat("it's ", '"', "quoted", '"', "")`,
+		`"`:             `'"'`,
+	}
+	for in, want := range tests {
+		if got := xpathLiteral(in); got != want {
+			t.Errorf("%q: got %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestTextXPath(t *testing.T) {
+	if x := textXPath("  Sign   in ", true); x != `//*[normalize-space(text())="Sign in"]` {
+		t.Errorf("exact: %s", x)
+	}
+	if x := textXPath(`Don't "quote"`, false); x != `//*[contains(normalize-space(text()), concat("Don't ", '"', "quote", '"', ""))]` {
+		t.Errorf("substring: %s", x)
+	}
+}
+
+func TestAttributeSelector(t *testing.T) {
+	for _, test := range []struct {
+		attr, value string
+		match       MatchType
+		want        string
+	}{
+		{"id", "order-", StartsWith, `[id^="order-"]`},
+		{"data-test", "save", Exact, `[data-test="save"]`},
+		{"href", ".pdf", EndsWith, `[href$=".pdf"]`},
+		{"title", `say "hi" \ bye`, Contains, `[title*="say \"hi\" \\ bye"]`},
+		{"title", "two\nlines", Exact, `[title="two\a lines"]`},
+		{"ng:model", "x", Exact, `[ng\:model="x"]`},
+		{"1x", "x", Exact, `[\31 x="x"]`},
+	} {
+		got, err := attributeSelector(test.attr, test.value, test.match)
+		if err != nil || got != test.want {
+			t.Errorf("%s %q: got %s, %v, want %s", test.attr, test.value, got, err, test.want)
+		}
+	}
+	if _, err := attributeSelector("", "x", Exact); err == nil {
+		t.Error("expected an error for an empty attribute")
+	}
+	if _, err := attributeSelector("id", "x", MatchType(9)); err == nil {
+		t.Error("expected an error for an invalid match type")
+	}
+}
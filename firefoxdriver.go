@@ -11,11 +11,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,10 +23,21 @@ type FirefoxDriver struct {
 	WebDriverCore
 	// The port firefox webdriver listens on. This port - 1 will be used as a mutex to avoid starting multiple firefox instances listening to the same port. Default: 7055
 	Port int
+	// If true Start uses exactly Port and fails if it is 0 or already in use,
+	// instead of picking a free port when Port is 0. Default: false
+	RequirePort bool
 	// Start method fails if lock (see Port) is not acquired before LockPortTimeout. Default 60s
 	LockPortTimeout time.Duration
 	// Start method fails if Firefox doesn't start in less than StartTimeout. Default 20s.
 	StartTimeout time.Duration
+	// Stop waits up to StopTimeout for Firefox to exit after the interrupt, then
+	// kills it. Default 10s.
+	StopTimeout time.Duration
+	// Working directory of the firefox process. If "" the current directory. Default: ""
+	Dir string
+	// Environment of the firefox process, as "KEY=value" strings. If nil the
+	// environment of the current process. Default: nil
+	Env []string
 	// Log file to dump firefox stdout/stderr. If "" send to terminal. Default: ""
 	LogFile string
 	// Firefox preferences. Default: see method GetDefaultPrefs
@@ -37,7 +48,7 @@ type FirefoxDriver struct {
 	firefoxPath string
 	xpiPath     string
 	profilePath string
-	cmd         *exec.Cmd
+	proc        *process
 	logFile     *os.File
 }
 
@@ -48,6 +59,7 @@ func NewFirefoxDriver(firefoxPath string, xpiPath string) *FirefoxDriver {
 	d.Port = 0
 	d.LockPortTimeout = 60 * time.Second
 	d.StartTimeout = 20 * time.Second
+	d.StopTimeout = 10 * time.Second
 	d.LogFile = ""
 	d.Prefs = GetDefaultPrefs()
 	d.DeleteProfileOnClose = true
@@ -75,8 +87,23 @@ func (d *FirefoxDriver) SetLogPath(path string) {
 	d.Prefs["webdriver.log.browser.file"] = filepath.Join(path, "browser.log")
 }
 
+// Set the user agent of the browser with the "general.useragent.override"
+// preference, in effect from the first request of the browser. Call it before
+// Start.
+func (d *FirefoxDriver) SetUserAgent(ua string) error {
+	if strings.TrimSpace(ua) == "" {
+		return errors.New("set user agent failed: empty user agent")
+	}
+	d.Prefs["general.useragent.override"] = ua
+	return nil
+}
+
 func (d *FirefoxDriver) Start() error {
-	if d.Port == 0 {
+	if d.RequirePort {
+		if err := checkPortFree("127.0.0.1", d.Port); err != nil {
+			return err
+		}
+	} else if d.Port == 0 {
 		var err error
 		d.Port, err = GetFreePort()
 		if err != nil {
@@ -84,6 +111,10 @@ func (d *FirefoxDriver) Start() error {
 		}
 	}
 
+	if err := d.VerifyBinary(); err != nil {
+		return errors.New("unable to start firefox: " + err.Error())
+	}
+
 	//start firefox with custom profile
 	//TODO it should be possible to use an existing profile
 	d.Prefs["webdriver_firefox_port"] = d.Port
@@ -93,55 +124,46 @@ func (d *FirefoxDriver) Start() error {
 		return err
 	}
 	debugprint(d.profilePath)
-	d.cmd = exec.Command(d.firefoxPath, "-no-remote", "-profile", d.profilePath)
-	stdout, err := d.cmd.StdoutPipe()
-	if err != nil {
-		fmt.Println(err)
-	}
-	stderr, err := d.cmd.StderrPipe()
-	if err != nil {
-		fmt.Println(err)
-	}
-	if err := d.cmd.Start(); err != nil {
-		return errors.New("unable to start firefox: " + err.Error())
-	}
+	cmd := exec.Command(d.firefoxPath, "-no-remote", "-profile", d.profilePath)
+	cmd.Dir = d.Dir
+	cmd.Env = d.Env
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
 	if d.LogFile != "" {
 		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
 		d.logFile, err = os.OpenFile(d.LogFile, flags, 0640)
 		if err != nil {
 			return err
 		}
-		go func() {
-			if _, err := io.Copy(d.logFile, stdout); err != nil {
-				log.Println(err)
-			}
-		}()
-		go func() {
-			if _, err := io.Copy(d.logFile, stderr); err != nil {
-				log.Println(err)
-			}
-		}()
-	} else {
-		go func() {
-			if _, err := io.Copy(os.Stdout, stdout); err != nil {
-				log.Println(err)
-			}
-		}()
-		go func() {
-			if _, err := io.Copy(os.Stderr, stderr); err != nil {
-				log.Println(err)
-			}
-		}()
+		stdout, stderr = d.logFile, d.logFile
 	}
-	//probe d.Port until firefox replies or StartTimeout is up
-	if err = probePort(d.Port, d.StartTimeout); err != nil {
-		return err
+	d.proc, err = startProcess(cmd, stdout, stderr)
+	if err == nil {
+		err = d.proc.waitStart(d.Port, d.StartTimeout)
+	}
+	if err != nil {
+		abortStart(d.proc, d.logFile, d.StopTimeout)
+		d.proc, d.logFile = nil, nil
+		if d.DeleteProfileOnClose {
+			os.RemoveAll(d.profilePath)
+		}
+		return errors.New("unable to start firefox: " + err.Error())
 	}
 
 	d.url = fmt.Sprintf("http://127.0.0.1:%d/hub", d.Port)
 	return nil
 }
 
+// Check that the firefox binary exists and is executable. A bare name
+// (e.g. "firefox") is resolved using PATH. Called by Start.
+func (d *FirefoxDriver) VerifyBinary() error {
+	path, err := verifyBinary(d.firefoxPath, "firefox")
+	if err != nil {
+		return err
+	}
+	d.firefoxPath = path
+	return nil
+}
+
 // Populate a map with default firefox preferences
 func GetDefaultPrefs() map[string]interface{} {
 	prefs := map[string]interface{}{
@@ -333,50 +355,33 @@ func writeExtensionFile(f *zip.File, extPath string) error {
 	return nil
 }
 
+// Stop firefox, if running, and start it again with the same configuration,
+// e.g. to recover from a browser crash. Sessions of the previous run are lost.
+// On failure the driver is left stopped.
+func (d *FirefoxDriver) Restart() error {
+	return restartDriver(d, d.proc != nil)
+}
+
 func (d *FirefoxDriver) Stop() error {
-	if d.cmd == nil {
+	if d.proc == nil {
 		return errors.New("stop failed: firefoxdriver not running")
 	}
 	defer func() {
-		d.cmd = nil
+		d.proc = nil
 	}()
-	if err := d.cmd.Process.Signal(os.Interrupt); err != nil {
-		return err
-	}
+	err := stopProcess(d.proc, d.StopTimeout)
 	if d.logFile != nil {
-		if err := d.logFile.Close(); err != nil {
-			return err
+		if cerr := d.logFile.Close(); cerr != nil && err == nil {
+			err = cerr
 		}
 	}
 	if d.DeleteProfileOnClose {
-		if err := os.RemoveAll(d.profilePath); err != nil {
-			return err
+		if rerr := os.RemoveAll(d.profilePath); rerr != nil && err == nil {
+			err = rerr
 		}
 	}
-	return nil
-}
-
-func (d *FirefoxDriver) NewSession(desired, required Capabilities) (*Session, error) {
-	session, err := d.newSession(desired, required)
 	if err != nil {
-		return nil, err
+		return errors.New("stop failed: " + err.Error())
 	}
-	session.wd = d
-	return session, nil
-}
-
-func (d *FirefoxDriver) Sessions() ([]Session, error) {
-	sessions, err := d.sessions()
-	if err != nil {
-		return nil, err
-	}
-	for i := range sessions {
-		sessions[i].wd = d
-	}
-	return sessions, nil
+	return nil
 }
-
-/*func (d *FirefoxDriver) NewSession(desired, required Capabilities) (*Session, error) {
-	id, capabs, err := d.newSession(desired, required)
-	return &Session{id, capabs, d}, err
-}*/
@@ -0,0 +1,250 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Fill el with value according to its kind, e.g. to fill a form from a map of
+// values:
+//
+//	text inputs, textarea   cleared, then value is typed
+//	checkbox                checked if value is "true", unchecked if "false"
+//	radio                   selected (value "false" is an error)
+//	select                  the option whose text or value attribute is value is chosen
+//	file input              value is the path of the file to upload
+//	contenteditable         focused and value is typed at the cursor
+//
+// Other elements are an error.
+func (s Session) Type(el WebElement, value string) error {
+	tag, err := el.Name()
+	if err != nil {
+		return err
+	}
+	tag = strings.ToLower(tag)
+	switch tag {
+	case "textarea":
+		return clearAndType(el, value)
+	case "select":
+		lit := xpathLiteral(value)
+		option, err := el.FindElement(XPath, ".//option[normalize-space(.)="+lit+" or @value="+lit+"]")
+		if err != nil {
+			return err
+		}
+		return option.Click()
+	case "input":
+		typ, err := el.GetAttribute("type")
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(typ) {
+		case "", "text", "email", "password", "search", "tel", "url", "number":
+			return clearAndType(el, value)
+		case "checkbox":
+			if value != "true" && value != "false" {
+				return errors.New("type failed: checkbox value must be \"true\" or \"false\": " + value)
+			}
+			return setSelected(el, value == "true")
+		case "radio":
+			if value == "false" {
+				return errors.New("type failed: a radio button can't be unselected")
+			}
+			return setSelected(el, true)
+		case "file":
+			return el.SendKeys(value)
+		}
+		return errors.New("type failed: unsupported input type: " + typ)
+	}
+	var editable bool
+	if err := s.executeScript("return arguments[0].isContentEditable;", []interface{}{el}, &editable); err != nil {
+		return err
+	}
+	if !editable {
+		return errors.New("type failed: unsupported element: " + tag)
+	}
+	if err := s.executeScript("arguments[0].focus();", []interface{}{el}, nil); err != nil {
+		return err
+	}
+	return el.SendKeys(value)
+}
+
+func clearAndType(el WebElement, value string) error {
+	if err := el.Clear(); err != nil {
+		return err
+	}
+	return el.SendKeys(value)
+}
+
+// click el if its selected state is not selected.
+func setSelected(el WebElement, selected bool) error {
+	current, err := el.IsSelected()
+	if err != nil {
+		return err
+	}
+	if current == selected {
+		return nil
+	}
+	return el.Click()
+}
+
+// Select the files at paths in a file input, sending them joined by newlines in
+// a single SendKeys as browsers expect for multiple files. More than one path
+// requires an input with the multiple attribute; a single path is accepted by
+// any file input, so the attribute is not checked.
+// Paths are read by the driver: if Session.UploadFiles is set each file is
+// first uploaded to the machine of the driver and its path there is sent.
+func (e WebElement) SendKeysFiles(paths ...string) error {
+	if len(paths) == 0 {
+		return errors.New("send files failed: no file")
+	}
+	if len(paths) > 1 {
+		multiple, err := e.GetAttribute("multiple")
+		if err != nil {
+			return err
+		}
+		if multiple == "" || multiple == "false" {
+			return errors.New("send files failed: input doesn't accept multiple files")
+		}
+	}
+	if e.s.UploadFiles {
+		remote := make([]string, len(paths))
+		for i, path := range paths {
+			var err error
+			if remote[i], err = e.s.uploadFile(path); err != nil {
+				return errors.New("send files failed: " + err.Error())
+			}
+		}
+		paths = remote
+	}
+	return e.SendKeys(strings.Join(paths, "\n"))
+}
+
+// upload the file at path to the machine of the driver, zipped as the
+// Selenium file command expects, and return its path there.
+func (s Session) uploadFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	p := params{"file": base64.StdEncoding.EncodeToString(buf.Bytes())}
+	// Selenium 4 serves the W3C sessions at its own prefix
+	endpoint := "/session/%s/file"
+	if s.w3c {
+		endpoint = "/session/%s/se/file"
+	}
+	_, data, err := s.do(p, "POST", endpoint, s.Id)
+	if err != nil {
+		return "", err
+	}
+	var remote string
+	if err := json.Unmarshal(data, &remote); err != nil {
+		return "", err
+	}
+	return remote, nil
+}
+
+// Clear the input (or textarea) and check that it is empty. Clear doesn't
+// always stick, e.g. components controlled by React restore their state, so
+// the value is read back and, if not empty, the field is cleared with the
+// keyboard too: select all (Ctrl+A, Cmd+A on macOS) followed by Backspace, as
+// a user would. An error is returned if the field is still not empty.
+func (e WebElement) ClearAndVerify() error {
+	if err := e.Clear(); err != nil {
+		return err
+	}
+	value, err := e.currentValue()
+	if err != nil || value == "" {
+		return err
+	}
+	modifier := ControlKey
+	for _, key := range []string{"platformName", "platform"} {
+		if platform, _ := e.s.Capabilities.GetString(key); strings.EqualFold(platform, "mac") {
+			modifier = MetaKey
+		}
+	}
+	if err := e.SendKeys(modifier + "a" + NullKey + BackspaceKey); err != nil {
+		return err
+	}
+	value, err = e.currentValue()
+	if err != nil {
+		return err
+	}
+	if value != "" {
+		return errors.New("clear failed: field still contains " + strconv.Quote(value))
+	}
+	return nil
+}
+
+// the current value of a form field, its value property (the value attribute
+// is only the initial value).
+func (e WebElement) currentValue() (string, error) {
+	return e.s.ExecuteScriptString("return arguments[0].value;", []interface{}{e})
+}
+
+const setRangeValueScript = `var el = arguments[0], value = arguments[1];
+if (el.tagName.toLowerCase() !== "input" || el.type !== "range") return "element is not a range input";
+var min = el.min === "" ? 0 : parseFloat(el.min), max = el.max === "" ? 100 : parseFloat(el.max);
+if (value < min || value > max) return "value " + value + " outside range " + min + "-" + max;
+// the native setter, so that frameworks tracking the value (React) see the change
+Object.getOwnPropertyDescriptor(HTMLInputElement.prototype, "value").set.call(el, String(value));
+el.dispatchEvent(new Event("input", {bubbles: true}));
+el.dispatchEvent(new Event("change", {bubbles: true}));
+return null;`
+
+// Set the value of a range input (a slider) and dispatch the input and change
+// events, so that frameworks react as to a user. Dragging the thumb with the
+// pointer can't hit an exact value, this intentionally bypasses the pointer
+// simulation. As in the browser, the value is rounded to the step of the
+// input. Values outside its min and max are an error.
+func (e WebElement) SetRangeValue(value float64) error {
+	var errMsg string
+	if err := e.s.executeScript(setRangeValueScript, []interface{}{e, value}, &errMsg); err != nil {
+		return err
+	}
+	if errMsg != "" {
+		return errors.New("set range value failed: " + errMsg)
+	}
+	return nil
+}
+
+const rangeValueScript = `var el = arguments[0];
+if (el.tagName.toLowerCase() !== "input" || el.type !== "range") return null;
+return [el.min === "" ? 0 : parseFloat(el.min), el.max === "" ? 100 : parseFloat(el.max), parseFloat(el.value)];`
+
+// Read the bounds and the current value of a range input. The bounds default
+// to 0 and 100 when the min and max attributes are missing, as in the browser.
+func (e WebElement) RangeValue() (min, max, value float64, err error) {
+	var v *[3]float64
+	if err := e.s.executeScript(rangeValueScript, []interface{}{e}, &v); err != nil {
+		return 0, 0, 0, err
+	}
+	if v == nil {
+		return 0, 0, 0, errors.New("range value failed: element is not a range input")
+	}
+	return v[0], v[1], v[2], nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestType(t *testing.T) {
+	elements := map[string]map[string]interface{}{
+		"text":    {"name": "input", "attribute/type": "email"},
+		"check":   {"name": "input", "attribute/type": "checkbox", "selected": false},
+		"checked": {"name": "input", "attribute/type": "checkbox", "selected": true},
+		"div":     {"name": "div"},
+	}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		for id, replies := range elements {
+			for cmd, v := range replies {
+				if r.Path == "/session/fake-session/element/"+id+"/"+cmd {
+					return v
+				}
+			}
+		}
+		if r.Path == "/session/fake-session/execute" {
+			return false
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Type(WebElement{session, "text"}, "a@b.c"); err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Path != "/session/fake-session/element/text/value" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	if err := session.Type(WebElement{session, "check"}, "true"); err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Path != "/session/fake-session/element/check/click" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	if err := session.Type(WebElement{session, "checked"}, "true"); err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Path != "/session/fake-session/element/checked/selected" {
+		t.Errorf("checked checkbox clicked: %+v", r)
+	}
+	if err := session.Type(WebElement{session, "check"}, "yes"); err == nil {
+		t.Error("invalid checkbox value accepted")
+	}
+	if err := session.Type(WebElement{session, "div"}, "x"); err == nil {
+		t.Error("not editable element accepted")
+	}
+}
+
+func TestSendKeysFiles(t *testing.T) {
+	multiple := map[string]interface{}{"single": nil, "multi": "true"}
+	uploaded := map[string]string{}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		for id, v := range multiple {
+			if r.Path == "/session/fake-session/element/"+id+"/attribute/multiple" {
+				return v
+			}
+		}
+		if r.Path == "/session/fake-session/file" {
+			data, _ := base64.StdEncoding.DecodeString(r.Body["file"].(string))
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil || len(zr.File) != 1 {
+				t.Errorf("invalid upload: %v", err)
+				return nil
+			}
+			rc, _ := zr.File[0].Open()
+			content, _ := ioutil.ReadAll(rc)
+			rc.Close()
+			uploaded[zr.File[0].Name] = string(content)
+			return "/remote/" + zr.File[0].Name
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (WebElement{session, "single"}).SendKeysFiles("/tmp/a.txt", "/tmp/b.txt"); err == nil {
+		t.Error("multiple files accepted without the multiple attribute")
+	}
+	if err := (WebElement{session, "multi"}).SendKeysFiles("/tmp/a.txt", "/tmp/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	r := f.last()
+	var sent string
+	for _, k := range r.Body["value"].([]interface{}) {
+		sent += k.(string)
+	}
+	if r.Path != "/session/fake-session/element/multi/value" || sent != "/tmp/a.txt\n/tmp/b.txt" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+	// any file input accepts one file
+	if err := (WebElement{session, "single"}).SendKeysFiles("/tmp/a.txt"); err != nil {
+		t.Errorf("single file: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	ioutil.WriteFile(a, []byte("aaa"), 0644)
+	ioutil.WriteFile(b, []byte("bbb"), 0644)
+	session.UploadFiles = true
+	if err := (WebElement{session, "multi"}).SendKeysFiles(a, b); err != nil {
+		t.Fatal(err)
+	}
+	sent = ""
+	for _, k := range f.last().Body["value"].([]interface{}) {
+		sent += k.(string)
+	}
+	if sent != "/remote/a.txt\n/remote/b.txt" || uploaded["a.txt"] != "aaa" || uploaded["b.txt"] != "bbb" {
+		t.Errorf("sent %q after uploading %v", sent, uploaded)
+	}
+	if err := (WebElement{session, "multi"}).SendKeysFiles(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("missing file uploaded")
+	}
+}
+
+func TestClearAndVerify(t *testing.T) {
+	values := map[string]string{"plain": "x", "controlled": "x", "stuck": "x"}
+	var keys []interface{}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch {
+		case r.Path == "/session/fake-session/execute":
+			id := r.Body["args"].([]interface{})[0].(map[string]interface{})["ELEMENT"].(string)
+			return values[id]
+		case strings.HasSuffix(r.Path, "/clear"):
+			if id := strings.Split(r.Path, "/")[4]; id == "plain" {
+				values[id] = ""
+			}
+		case strings.HasSuffix(r.Path, "/value"):
+			keys = r.Body["value"].([]interface{})
+			if id := strings.Split(r.Path, "/")[4]; id == "controlled" {
+				values[id] = ""
+			}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (WebElement{session, "plain"}).ClearAndVerify(); err != nil || keys != nil {
+		t.Errorf("plain: %v, keys %q", err, keys)
+	}
+	if err := (WebElement{session, "controlled"}).ClearAndVerify(); err != nil {
+		t.Errorf("controlled: %v", err)
+	}
+	want := []interface{}{ControlKey, "a", NullKey, BackspaceKey}
+	if len(keys) != len(want) {
+		t.Fatalf("unexpected keys: %q", keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("unexpected keys: %q", keys)
+		}
+	}
+	if err := (WebElement{session, "stuck"}).ClearAndVerify(); err == nil {
+		t.Error("expected an error for a field that can't be cleared")
+	}
+}
+
+func TestRangeValue(t *testing.T) {
+	value := 50.0
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path != "/session/fake-session/execute" {
+			return nil
+		}
+		args := r.Body["args"].([]interface{})
+		if args[0].(map[string]interface{})["ELEMENT"] == "text" {
+			return "element is not a range input"
+		}
+		if len(args) == 2 {
+			value = args[1].(float64)
+			return nil
+		}
+		return []float64{0, 10, value}
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slider := WebElement{session, "slider"}
+	if err := slider.SetRangeValue(7.5); err != nil {
+		t.Fatal(err)
+	}
+	if min, max, v, err := slider.RangeValue(); err != nil || min != 0 || max != 10 || v != 7.5 {
+		t.Errorf("got %g, %g, %g, %v", min, max, v, err)
+	}
+	if err := (WebElement{session, "text"}).SetRangeValue(1); err == nil || !strings.Contains(err.Error(), "not a range input") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -1,7 +1,10 @@
 package webdriver
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"strconv"
 )
 
 // Ask the kernel for a free open port that is ready to use
@@ -17,3 +20,15 @@ func GetFreePort() (int, error) {
 	}
 	return l.Addr().(*net.TCPAddr).Port, nil
 }
+
+// Check that port can be bound on host, returns a "port in use" error otherwise.
+func checkPortFree(host string, port int) error {
+	if port == 0 {
+		return errors.New("port required but not set")
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("port %d in use: %s", port, err)
+	}
+	return l.Close()
+}
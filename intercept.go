@@ -0,0 +1,164 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// A request paused by InterceptRequests.
+type InterceptedRequest struct {
+	Url     string
+	Method  string
+	Headers map[string]string
+	// Body of POST requests, if any.
+	PostData string
+	// CDP resource type, e.g. "Document", "Script", "XHR", "Fetch", "Image".
+	ResourceType string
+}
+
+// What to do with an intercepted request, see Continue, Fail and Fulfill.
+type InterceptAction struct {
+	fail    bool
+	fulfill bool
+	status  int
+	headers map[string]string
+	body    []byte
+}
+
+// Let the request reach the network unchanged.
+func Continue() InterceptAction {
+	return InterceptAction{}
+}
+
+// Make the request fail with a network error, as if it was blocked.
+func Fail() InterceptAction {
+	return InterceptAction{fail: true}
+}
+
+// Answer the request with the given response, without reaching the network.
+func Fulfill(status int, headers map[string]string, body []byte) InterceptAction {
+	return InterceptAction{fulfill: true, status: status, headers: headers, body: body}
+}
+
+// Intercept the requests of the current page whose url matches pattern, and
+// decide with handler whether each one continues, fails or gets a mocked
+// response, e.g. to mock an API:
+//
+//	err := session.InterceptRequests("*/api/cart*", func(r webdriver.InterceptedRequest) webdriver.InterceptAction {
+//		return webdriver.Fulfill(200, map[string]string{"Content-Type": "application/json"}, []byte(`{"items":[]}`))
+//	})
+//
+// pattern is a CDP Fetch url pattern: "*" matches any sequence of characters,
+// "?" a single one, "" matches every request. Requests are paused until
+// handler returns: it is called on its own goroutine for each request, so it
+// can be called concurrently, and should return quickly.
+//
+// Interception uses the CDP Fetch domain on a DevTools connection with the
+// page of the current window, other windows are not intercepted. Only one
+// interception can run at a time, stop it with StopInterceptRequests.
+// Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) InterceptRequests(pattern string, handler func(req InterceptedRequest) InterceptAction) error {
+	st, err := s.sharedState()
+	if err != nil {
+		return errors.New("intercept requests failed: " + err.Error())
+	}
+	st.cdpMu.Lock()
+	defer st.cdpMu.Unlock()
+	if st.intercept != nil {
+		return errors.New("intercept requests failed: interception already running")
+	}
+	if pattern == "" {
+		pattern = "*"
+	}
+	conn, err := s.cdpConnect()
+	if err != nil {
+		return err
+	}
+	conn.on("Fetch.requestPaused", func(raw json.RawMessage) {
+		var event struct {
+			RequestId string `json:"requestId"`
+			Request   struct {
+				Url      string            `json:"url"`
+				Method   string            `json:"method"`
+				Headers  map[string]string `json:"headers"`
+				PostData string            `json:"postData"`
+			} `json:"request"`
+			ResourceType string `json:"resourceType"`
+		}
+		if err := json.Unmarshal(raw, &event); err != nil {
+			debugprint("intercept requests: " + err.Error())
+			return
+		}
+		req := InterceptedRequest{
+			Url:          event.Request.Url,
+			Method:       event.Request.Method,
+			Headers:      event.Request.Headers,
+			PostData:     event.Request.PostData,
+			ResourceType: event.ResourceType,
+		}
+		go func() {
+			method, args := handler(req).command(event.RequestId)
+			if err := conn.send(method, args); err != nil {
+				debugprint("intercept requests: " + err.Error())
+			}
+		}()
+	})
+	args := map[string]interface{}{
+		"patterns": []map[string]interface{}{{"urlPattern": pattern, "requestStage": "Request"}},
+	}
+	if err := conn.call("Fetch.enable", args, nil); err != nil {
+		conn.Close()
+		return err
+	}
+	st.intercept = conn
+	return nil
+}
+
+// the CDP command carrying out the action for the paused request id.
+func (a InterceptAction) command(id string) (string, map[string]interface{}) {
+	switch {
+	case a.fail:
+		return "Fetch.failRequest", map[string]interface{}{"requestId": id, "errorReason": "Failed"}
+	case a.fulfill:
+		// sorted to send a stable header order
+		names := make([]string, 0, len(a.headers))
+		for name := range a.headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		headers := make([]map[string]string, len(names))
+		for i, name := range names {
+			headers[i] = map[string]string{"name": name, "value": a.headers[name]}
+		}
+		return "Fetch.fulfillRequest", map[string]interface{}{
+			"requestId":       id,
+			"responseCode":    a.status,
+			"responseHeaders": headers,
+			"body":            base64.StdEncoding.EncodeToString(a.body),
+		}
+	}
+	return "Fetch.continueRequest", map[string]interface{}{"requestId": id}
+}
+
+// Stop the interception started with InterceptRequests, also through another
+// copy of the session; requests are no longer paused.
+func (s Session) StopInterceptRequests() error {
+	st, err := s.sharedState()
+	if err != nil {
+		return errors.New("stop intercept requests failed: " + err.Error())
+	}
+	st.cdpMu.Lock()
+	conn := st.intercept
+	st.intercept = nil
+	st.cdpMu.Unlock()
+	if conn == nil {
+		return errors.New("stop intercept requests failed: interception not running")
+	}
+	return conn.Close()
+}
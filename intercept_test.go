@@ -0,0 +1,90 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterceptRequests(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/window_handle" {
+			return "T1"
+		}
+		return nil
+	})
+	actions := make(chan cdpMessage, 3)
+	d := newFakeDevTools(t, func(c *wsConn, m cdpMessage) {
+		switch m.Method {
+		case "Fetch.enable":
+			if !strings.Contains(string(m.Params), `"urlPattern":"*/api/*"`) {
+				t.Errorf("unexpected Fetch.enable params: %s", m.Params)
+			}
+			writeCDP(t, c, cdpMessage{Id: m.Id, Result: json.RawMessage("{}")})
+			for _, event := range []string{
+				`{"requestId":"1","request":{"url":"http://a/api/cart","method":"GET","headers":{}},"resourceType":"XHR"}`,
+				`{"requestId":"2","request":{"url":"http://a/api/ads","method":"GET","headers":{}},"resourceType":"XHR"}`,
+				`{"requestId":"3","request":{"url":"http://a/api/user","method":"POST","headers":{},"postData":"x=1"},"resourceType":"Fetch"}`,
+			} {
+				writeCDP(t, c, cdpMessage{Method: "Fetch.requestPaused", Params: json.RawMessage(event)})
+			}
+		default:
+			actions <- m
+		}
+	})
+	session := newFakeCDPSession(t, f, d)
+	err := session.InterceptRequests("*/api/*", func(r InterceptedRequest) InterceptAction {
+		switch {
+		case strings.HasSuffix(r.Url, "/cart"):
+			return Fulfill(200, map[string]string{"Content-Type": "application/json"}, []byte(`{"items":[]}`))
+		case strings.HasSuffix(r.Url, "/ads"):
+			return Fail()
+		}
+		if r.PostData != "x=1" {
+			t.Errorf("unexpected post data: %q", r.PostData)
+		}
+		return Continue()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]map[string]interface{}{}
+	for i := 0; i < 3; i++ {
+		select {
+		case m := <-actions:
+			var args map[string]interface{}
+			json.Unmarshal(m.Params, &args)
+			args["method"] = m.Method
+			got[args["requestId"].(string)] = args
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for the intercept actions")
+		}
+	}
+	body, _ := base64.StdEncoding.DecodeString(got["1"]["body"].(string))
+	if got["1"]["method"] != "Fetch.fulfillRequest" || got["1"]["responseCode"] != float64(200) || string(body) != `{"items":[]}` {
+		t.Errorf("unexpected fulfill: %v", got["1"])
+	}
+	if got["2"]["method"] != "Fetch.failRequest" {
+		t.Errorf("unexpected fail: %v", got["2"])
+	}
+	if got["3"]["method"] != "Fetch.continueRequest" {
+		t.Errorf("unexpected continue: %v", got["3"])
+	}
+	// the interception is shared by the copies of the session
+	copied := *session
+	if err := copied.InterceptRequests("*", nil); err == nil {
+		t.Error("second interception accepted")
+	}
+	if err := copied.StopInterceptRequests(); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.StopInterceptRequests(); err == nil {
+		t.Error("stopped interception stopped again")
+	}
+}
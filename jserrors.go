@@ -0,0 +1,35 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Return an error listing the SEVERE entries (uncaught exceptions, failed
+// console.error...) of the browser log since the last call.
+// Reading the browser log consumes it, so each entry is reported only once.
+//
+// The browser log must be enabled when the session is created, e.g. for
+// chromedriver with the capability "goog:loggingPrefs": {"browser": "ALL"}
+// (older versions use "loggingPrefs"). If the driver can't provide the log an
+// error is returned instead of passing silently.
+func (s Session) AssertNoJSErrors() error {
+	entries, err := s.Log("browser")
+	if err != nil {
+		return fmt.Errorf("browser log not available, enable it with the loggingPrefs capability: %s", err)
+	}
+	var severe []string
+	for _, entry := range entries {
+		if entry.Level == string(LogSevere) {
+			severe = append(severe, entry.Message)
+		}
+	}
+	if len(severe) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d javascript errors:\n\t%s", len(severe), strings.Join(severe, "\n\t"))
+}
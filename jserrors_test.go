@@ -0,0 +1,41 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssertNoJSErrors(t *testing.T) {
+	var log interface{}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/log" {
+			return log
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log = []map[string]interface{}{{"level": "INFO", "message": "loaded"}}
+	if err := session.AssertNoJSErrors(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	log = []map[string]interface{}{
+		{"level": "SEVERE", "message": "Uncaught TypeError: x is undefined"},
+		{"level": "WARNING", "message": "deprecated"},
+		{"level": "SEVERE", "message": "404 favicon.ico"},
+	}
+	err = session.AssertNoJSErrors()
+	if err == nil || !strings.Contains(err.Error(), "2 javascript errors") || !strings.Contains(err.Error(), "x is undefined") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	log = &CommandError{StatusCode: UnknownCommand, Message: "unknown command"}
+	if err := session.AssertNoJSErrors(); err == nil || !strings.Contains(err.Error(), "loggingPrefs") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
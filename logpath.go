@@ -0,0 +1,42 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"os"
+)
+
+// What Start does with the existing driver log at LogPath.
+type LogPathMode int
+
+const (
+	// Append to the log. If MaxLogSize is set and the log is bigger, it is
+	// rotated first.
+	LogPathAppend LogPathMode = iota
+	// Empty the log.
+	LogPathTruncate
+	// Rename the log to LogPath + ".1", replacing the previous one.
+	LogPathRotate
+)
+
+// prepare the log at path according to mode and check that it is writable.
+func prepareLogPath(path string, mode LogPathMode, maxSize int64) error {
+	info, err := os.Stat(path)
+	exists := err == nil && info.Size() > 0
+	if exists && (mode == LogPathRotate || mode == LogPathAppend && maxSize > 0 && info.Size() > maxSize) {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+	flags := os.O_WRONLY | os.O_CREATE
+	if mode == LogPathTruncate {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0664)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
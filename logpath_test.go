@@ -0,0 +1,74 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrepareLogPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "driver.log")
+	read := func(path string) string {
+		data, _ := ioutil.ReadFile(path)
+		return string(data)
+	}
+	tests := []struct {
+		mode         LogPathMode
+		maxSize      int64
+		log, rotated string
+	}{
+		{LogPathAppend, 0, "old log", ""},
+		{LogPathAppend, 100, "old log", ""},
+		{LogPathAppend, 3, "", "old log"},
+		{LogPathTruncate, 0, "", ""},
+		{LogPathRotate, 0, "", "old log"},
+	}
+	for _, test := range tests {
+		os.Remove(path + ".1")
+		if err := ioutil.WriteFile(path, []byte("old log"), 0664); err != nil {
+			t.Fatal(err)
+		}
+		if err := prepareLogPath(path, test.mode, test.maxSize); err != nil {
+			t.Fatal(err)
+		}
+		if log, rotated := read(path), read(path+".1"); log != test.log || rotated != test.rotated {
+			t.Errorf("mode %d, max size %d: log %q, rotated %q", test.mode, test.maxSize, log, rotated)
+		}
+	}
+}
+
+func TestChromeDriverAppendLog(t *testing.T) {
+	hasAppendLog := func(d *ChromeDriver) bool {
+		for _, s := range d.switches() {
+			if s == "-append-log" {
+				return true
+			}
+		}
+		return false
+	}
+	d := NewChromeDriver("chromedriver")
+	if !hasAppendLog(d) {
+		t.Errorf("default mode doesn't append: %q", d.switches())
+	}
+	for _, mode := range []LogPathMode{LogPathTruncate, LogPathRotate} {
+		d.LogPathMode = mode
+		if hasAppendLog(d) {
+			t.Errorf("mode %d appends: %q", mode, d.switches())
+		}
+	}
+	d.LogPathMode = LogPathAppend
+	d.LogPath = ""
+	if hasAppendLog(d) {
+		t.Errorf("append without a log path: %q", d.switches())
+	}
+}
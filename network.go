@@ -0,0 +1,137 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// HTTP response of a navigation.
+type ResponseInfo struct {
+	Url        string
+	Status     int
+	StatusText string
+	// Headers with multiple values have them joined by "\n".
+	Headers map[string]string
+}
+
+// network events recorded on a CDP connection.
+type networkCapture struct {
+	conn      *cdpConn
+	mainFrame string
+
+	mu       sync.Mutex
+	response *ResponseInfo
+}
+
+// Start recording the network activity of the current page with the CDP
+// Network domain, needed by LastResponse. Navigations that happened before
+// the capture started are not seen.
+// Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) StartNetworkCapture() error {
+	st, err := s.sharedState()
+	if err != nil {
+		return errors.New("start network capture failed: " + err.Error())
+	}
+	st.cdpMu.Lock()
+	defer st.cdpMu.Unlock()
+	if st.network != nil {
+		return errors.New("start network capture failed: capture already running")
+	}
+	conn, err := s.cdpConnect()
+	if err != nil {
+		return err
+	}
+	var tree struct {
+		FrameTree struct {
+			Frame struct {
+				Id string `json:"id"`
+			} `json:"frame"`
+		} `json:"frameTree"`
+	}
+	if err := conn.call("Page.getFrameTree", nil, &tree); err != nil {
+		conn.Close()
+		return err
+	}
+	nc := &networkCapture{conn: conn, mainFrame: tree.FrameTree.Frame.Id}
+	conn.on("Network.responseReceived", nc.responseReceived)
+	if err := conn.call("Network.enable", nil, nil); err != nil {
+		conn.Close()
+		return err
+	}
+	st.network = nc
+	return nil
+}
+
+func (nc *networkCapture) responseReceived(raw json.RawMessage) {
+	var event struct {
+		Type     string `json:"type"`
+		FrameId  string `json:"frameId"`
+		Response struct {
+			Url        string            `json:"url"`
+			Status     int               `json:"status"`
+			StatusText string            `json:"statusText"`
+			Headers    map[string]string `json:"headers"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(raw, &event); err != nil {
+		debugprint("network capture: " + err.Error())
+		return
+	}
+	if event.Type != "Document" || event.FrameId != nc.mainFrame {
+		return
+	}
+	nc.mu.Lock()
+	nc.response = &ResponseInfo{
+		Url:        event.Response.Url,
+		Status:     event.Response.Status,
+		StatusText: event.Response.StatusText,
+		Headers:    event.Response.Headers,
+	}
+	nc.mu.Unlock()
+}
+
+// Stop the network capture started with StartNetworkCapture, also through
+// another copy of the session.
+func (s Session) StopNetworkCapture() error {
+	st, err := s.sharedState()
+	if err != nil {
+		return errors.New("stop network capture failed: " + err.Error())
+	}
+	st.cdpMu.Lock()
+	nc := st.network
+	st.network = nil
+	st.cdpMu.Unlock()
+	if nc == nil {
+		return errors.New("stop network capture failed: capture not running")
+	}
+	return nc.conn.Close()
+}
+
+// Return status code and headers of the response of the last top-level
+// navigation (the document of the main frame, not iframes) seen since
+// StartNetworkCapture.
+//
+// WebDriver doesn't expose HTTP responses: this is Chrome only and needs the
+// capture to be started before navigating.
+func (s Session) LastResponse() (ResponseInfo, error) {
+	var nc *networkCapture
+	if st := s.state; st != nil {
+		st.cdpMu.Lock()
+		nc = st.network
+		st.cdpMu.Unlock()
+	}
+	if nc == nil {
+		return ResponseInfo{}, errors.New("last response failed: network capture not started")
+	}
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if nc.response == nil {
+		return ResponseInfo{}, errors.New("last response failed: no navigation captured")
+	}
+	return *nc.response, nil
+}
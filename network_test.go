@@ -0,0 +1,66 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLastResponse(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/window_handle" {
+			return "T1"
+		}
+		return nil
+	})
+	d := newFakeDevTools(t, func(c *wsConn, m cdpMessage) {
+		switch m.Method {
+		case "Page.getFrameTree":
+			writeCDP(t, c, cdpMessage{Id: m.Id, Result: json.RawMessage(`{"frameTree":{"frame":{"id":"F1"}}}`)})
+		case "Network.enable":
+			writeCDP(t, c, cdpMessage{Id: m.Id, Result: json.RawMessage("{}")})
+			for _, event := range []string{
+				`{"type":"Document","frameId":"F1","response":{"url":"http://a/","status":200,"headers":{"Cache-Control":"no-cache"}}}`,
+				`{"type":"Script","frameId":"F1","response":{"url":"http://a/app.js","status":200}}`,
+				`{"type":"Document","frameId":"F1","response":{"url":"http://a/login","status":302,"statusText":"Found","headers":{"Location":"/home"}}}`,
+				`{"type":"Document","frameId":"F2","response":{"url":"http://ads/","status":404}}`,
+			} {
+				writeCDP(t, c, cdpMessage{Method: "Network.responseReceived", Params: json.RawMessage(event)})
+			}
+		default:
+			writeCDP(t, c, cdpMessage{Id: m.Id, Result: json.RawMessage("{}")})
+		}
+	})
+	session := newFakeCDPSession(t, f, d)
+	if _, err := session.LastResponse(); err == nil {
+		t.Fatal("expected an error before starting the capture")
+	}
+	if err := session.StartNetworkCapture(); err != nil {
+		t.Fatal(err)
+	}
+	// the capture is shared by the copies of the session
+	copied := *session
+	var response ResponseInfo
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var err error
+		response, err = copied.LastResponse()
+		if err == nil && response.Url == "http://a/login" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if response.Url != "http://a/login" || response.Status != 302 || response.StatusText != "Found" || response.Headers["Location"] != "/home" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	if err := copied.StopNetworkCapture(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.LastResponse(); err == nil {
+		t.Error("expected an error after stopping the capture")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Maximum number of sessions that CreateSessions creates at the same time.
+// It is shared by all the calls: set it once, before CreateSessions may be
+// running (e.g. in TestMain, not in parallel tests). Default: 4
+var CreateSessionsLimit = 4
+
+// Create n sessions with capabilities caps, creating up to CreateSessionsLimit
+// of them concurrently. If a session can't be created, the ones already created
+// are deleted and the first error is returned. n zero returns no sessions and
+// a nil error, n negative an error.
+func CreateSessions(driver WebDriver, caps Capabilities, n int) ([]*Session, error) {
+	if n < 0 {
+		return nil, errors.New("create sessions failed: negative count: " + strconv.Itoa(n))
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	limit := CreateSessionsLimit
+	if limit < 1 {
+		limit = 1
+	}
+	sessions := make([]*Session, n)
+	errs := make([]error, n)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			sessions[i], errs[i] = driver.NewSession(caps, nil)
+		}(i)
+	}
+	wg.Wait()
+	var first error
+	for i, err := range errs {
+		if err != nil && first == nil {
+			first = fmt.Errorf("create sessions failed: session %d of %d: %w", i+1, n, err)
+		}
+	}
+	if first == nil {
+		return sessions, nil
+	}
+	for i, session := range sessions {
+		if errs[i] == nil && session != nil {
+			session.Delete()
+		}
+	}
+	return nil, first
+}
+
+// Create a session with capabilities caps, run fn with it and delete it, so
+// that no session is leaked by one-off scripts:
+//
+//	err := driver.WithSession(caps, func(s *webdriver.Session) error {
+//		return s.Url("https://example.com")
+//	})
+//
+// The session is deleted even if fn panics; the panic then continues once
+// the session is deleted. The deletion is best-effort: its error is returned
+// only if fn succeeded, it never replaces the error of fn.
+func (w *WebDriverCore) WithSession(caps Capabilities, fn func(*Session) error) error {
+	return withSession(w, caps, fn)
+}
+
+// see WebDriverCore.WithSession. The session is created with driver.NewSession,
+// so that drivers overriding it (e.g. ChromeDriver) are honored.
+func withSession(driver WebDriver, caps Capabilities, fn func(*Session) error) (err error) {
+	session, err := driver.NewSession(caps, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		derr := session.Delete()
+		if p := recover(); p != nil {
+			panic(p)
+		}
+		if err == nil && derr != nil {
+			err = fmt.Errorf("delete session failed: %w", derr)
+		}
+	}()
+	return fn(session)
+}
@@ -0,0 +1,96 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCreateSessions(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight, created, deleted int
+	fail := false
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session" {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			created++
+			n := created
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			if fail && n == 3 {
+				return &CommandError{StatusCode: SessionNotCreatedException, Message: "no more browsers"}
+			}
+			return map[string]interface{}{"browserName": "fake"}
+		}
+		if r.Method == "DELETE" {
+			mu.Lock()
+			deleted++
+			mu.Unlock()
+		}
+		return nil
+	})
+	defer func(limit int) { CreateSessionsLimit = limit }(CreateSessionsLimit)
+	CreateSessionsLimit = 2
+	sessions, err := CreateSessions(f.core(), nil, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 5 || maxInFlight > 2 {
+		t.Fatalf("%d sessions, %d concurrent creations", len(sessions), maxInFlight)
+	}
+	created, fail = 0, true
+	if _, err := CreateSessions(f.core(), nil, 5); err == nil {
+		t.Fatal("expected an error")
+	}
+	if deleted != 4 {
+		t.Errorf("%d sessions deleted, want 4", deleted)
+	}
+	created = 0
+	if sessions, err := CreateSessions(f.core(), nil, 0); sessions != nil || err != nil {
+		t.Errorf("zero sessions: got %v, %v", sessions, err)
+	}
+	if _, err := CreateSessions(f.core(), nil, -1); err == nil {
+		t.Error("negative count accepted")
+	}
+	if created != 0 {
+		t.Errorf("%d sessions created for no session", created)
+	}
+}
+
+func TestWithSession(t *testing.T) {
+	var deletes int
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Method == "DELETE" {
+			deletes++
+		}
+		return nil
+	})
+	fnErr := errors.New("fn failed")
+	var wd WebDriver = f.core()
+	if err := wd.WithSession(nil, func(s *Session) error { return fnErr }); err != fnErr || deletes != 1 {
+		t.Errorf("got %v after %d deletes", err, deletes)
+	}
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("unexpected panic: %v", p)
+			}
+		}()
+		wd.WithSession(nil, func(s *Session) error { panic("boom") })
+	}()
+	if deletes != 2 {
+		t.Errorf("session not deleted after a panic")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"time"
+)
+
+// How long a PatientSession returned by Session.Patient waits. Default: 10s
+var DefaultPatience = 10 * time.Second
+
+// A PatientSession wraps a Session, waiting for elements to be present (or
+// clickable before acting on them) up to Timeout. With a Timeout of 0, as
+// returned by Session.Impatient, each condition is checked once and missing
+// elements fail immediately. The state of the session (e.g. the implicit wait)
+// is not changed.
+//
+//	err := session.Patient().Click(webdriver.CSS_Selector, "button.save")
+type PatientSession struct {
+	Session Session
+	Timeout time.Duration
+}
+
+// A wrapper of the session waiting up to DefaultPatience.
+func (s Session) Patient() *PatientSession {
+	return &PatientSession{Session: s, Timeout: DefaultPatience}
+}
+
+// A wrapper of the session failing as soon as an element is missing or not
+// clickable.
+func (s Session) Impatient() *PatientSession {
+	return &PatientSession{Session: s}
+}
+
+// Search for an element, waiting for it to be present.
+func (p *PatientSession) FindElement(using FindElementStrategy, value string) (WebElement, error) {
+	var elem WebElement
+	err := poll(p.Timeout, func() (bool, error) {
+		we, err := p.Session.FindElement(using, value)
+		if isElementGone(err) {
+			return false, nil
+		}
+		elem = we
+		return err == nil, err
+	})
+	if err == ErrWaitTimeout {
+		return WebElement{}, &CommandError{StatusCode: NoSuchElement, Message: "no such element: " + string(using) + " " + value, Label: p.Session.Label()}
+	}
+	return elem, err
+}
+
+// Search for an element, waiting for it to be clickable (see
+// Session.WaitForClickable), and click it.
+func (p *PatientSession) Click(using FindElementStrategy, value string) error {
+	elem, err := p.Session.WaitForClickable(using, value, p.Timeout)
+	if err != nil {
+		return err
+	}
+	return elem.Click()
+}
+
+// Search for an element, waiting for it to be clickable, and send it sequence.
+func (p *PatientSession) SendKeys(using FindElementStrategy, value, sequence string) error {
+	elem, err := p.Session.WaitForClickable(using, value, p.Timeout)
+	if err != nil {
+		return err
+	}
+	return elem.SendKeys(sequence)
+}
+
+// Search for an element, waiting for it to be present, and return its text.
+func (p *PatientSession) Text(using FindElementStrategy, value string) (string, error) {
+	elem, err := p.FindElement(using, value)
+	if err != nil {
+		return "", err
+	}
+	return elem.Text()
+}
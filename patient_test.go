@@ -0,0 +1,46 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPatientSession(t *testing.T) {
+	var finds int
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/element":
+			finds++
+			if finds < 3 {
+				return &CommandError{StatusCode: NoSuchElement, Message: "no such element"}
+			}
+			return map[string]string{"ELEMENT": "1"}
+		case "/session/fake-session/element/1/displayed", "/session/fake-session/element/1/enabled":
+			return true
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	if _, err := session.Impatient().FindElement(ID, "save"); !errors.Is(err, ErrNoSuchElement) {
+		t.Fatalf("impatient find: expected ErrNoSuchElement, got %v", err)
+	}
+	if finds != 1 {
+		t.Errorf("impatient find searched %d times", finds)
+	}
+	if err := session.Patient().Click(ID, "save"); err != nil {
+		t.Fatal(err)
+	}
+	if r := f.last(); r.Path != "/session/fake-session/element/1/click" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/exec"
 	"time"
@@ -20,23 +19,39 @@ type PhantomJsDriver struct {
 	WebDriverCore
 	//The port that PhantomJsDriver listens on. Default: 9515
 	Port int
+	// If true Start uses exactly Port and fails if it is 0 or already in use,
+	// instead of picking a free port when Port is 0. Default: false
+	RequirePort bool
 	//The URL path prefix to use for all incoming WebDriver REST requests. Default: ""
 	BaseUrl string
 	//The number of threads to use for handling HTTP requests. Default: 4
 	Threads int
 	//The path to use for the PhantomJsDriver server log. Default: ./phantomJsdriver.log
 	LogPath string
+	// What Start does with an existing log at LogPath. Default: LogPathAppend
+	LogPathMode LogPathMode
+	// With LogPathAppend, the log at LogPath is rotated if bigger than
+	// MaxLogSize bytes. If 0 the log grows without limit. Default: 0
+	MaxLogSize int64
 	// Log file to dump phantomJsdriver stdout/stderr. If "" send to terminal. Default: ""
 	LogFile string
 	// Start method fails if PhantomJsdriver doesn't start in less than StartTimeout. Default 20s.
 	StartTimeout time.Duration
+	// Stop waits up to StopTimeout for PhantomJsdriver to exit after the interrupt, then
+	// kills it. Default 10s.
+	StopTimeout time.Duration
+	// Working directory of the phantomjs process. If "" the current directory. Default: ""
+	Dir string
+	// Environment of the phantomjs process, as "KEY=value" strings. If nil the
+	// environment of the current process. Default: nil
+	Env []string
 	// Host. Default 127.0.0.1
 	Host string
 	// LogLevel. Default DEBUG
 	LogLevel string
 
 	path    string
-	cmd     *exec.Cmd
+	proc    *process
 	logFile *os.File
 }
 
@@ -55,11 +70,16 @@ func NewPhantomJsDriver(path string) *PhantomJsDriver {
 	d.LogFile = "phantomJsOutput.log"
 	d.LogLevel = "DEBUG"
 	d.StartTimeout = 20 * time.Second
+	d.StopTimeout = 10 * time.Second
 	return d
 }
 
 func (d *PhantomJsDriver) Start() error {
-	if d.Port == 0 {
+	if d.RequirePort {
+		if err := checkPortFree(d.Host, d.Port); err != nil {
+			return err
+		}
+	} else if d.Port == 0 {
 		var err error
 		d.Port, err = GetFreePort()
 		if err != nil {
@@ -68,17 +88,17 @@ func (d *PhantomJsDriver) Start() error {
 	}
 
 	csferr := "phantomJsdriver start failed: "
-	if d.cmd != nil {
+	if d.proc != nil {
 		return errors.New(csferr + "phantomJsdriver already running")
 	}
+	if err := d.VerifyBinary(); err != nil {
+		return errors.New(csferr + err.Error())
+	}
 
 	if d.LogPath != "" {
-		//check if log-path is writable
-		file, err := os.OpenFile(d.LogPath, os.O_WRONLY|os.O_CREATE, 0664)
-		if err != nil {
+		if err := prepareLogPath(d.LogPath, d.LogPathMode, d.MaxLogSize); err != nil {
 			return errors.New(csferr + "unable to write in log path: " + err.Error())
 		}
-		file.Close()
 	}
 
 	d.url = fmt.Sprintf("http://%s:%d%s", d.Host, d.Port, d.BaseUrl)
@@ -87,92 +107,64 @@ func (d *PhantomJsDriver) Start() error {
 	switches = append(switches, fmt.Sprintf("--webdriver-logfile=%s", d.LogPath))
 	switches = append(switches, fmt.Sprintf("--webdriver-loglevel=%s", d.LogLevel))
 
-	d.cmd = exec.Command(d.path, switches...)
-	stdout, err := d.cmd.StdoutPipe()
-	if err != nil {
-		return errors.New(csferr + err.Error())
-	}
-	stderr, err := d.cmd.StderrPipe()
-	if err != nil {
-		return errors.New(csferr + err.Error())
-	}
-	if err := d.cmd.Start(); err != nil {
-		return errors.New(csferr + err.Error())
-	}
+	cmd := exec.Command(d.path, switches...)
+	cmd.Dir = d.Dir
+	cmd.Env = d.Env
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	var err error
 	if d.LogFile != "" {
 		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
 		d.logFile, err = os.OpenFile(d.LogFile, flags, 0640)
 		if err != nil {
 			return err
 		}
-		go func() {
-			if _, err := io.Copy(d.logFile, stdout); err != nil {
-				log.Println(err)
-			}
-		}()
-		go func() {
-			if _, err := io.Copy(d.logFile, stderr); err != nil {
-				log.Println(err)
-			}
-		}()
-	} else {
-		go func() {
-			if _, err := io.Copy(os.Stdout, stdout); err != nil {
-				log.Println(err)
-			}
-		}()
-		go func() {
-			if _, err := io.Copy(os.Stderr, stderr); err != nil {
-				log.Println(err)
-			}
-		}()
+		stdout, stderr = d.logFile, d.logFile
 	}
-	if err = probePort(d.Port, d.StartTimeout); err != nil {
-		return err
+	d.proc, err = startProcess(cmd, stdout, stderr)
+	if err == nil {
+		err = d.proc.waitStart(d.Port, d.StartTimeout)
+	}
+	if err != nil {
+		abortStart(d.proc, d.logFile, d.StopTimeout)
+		d.proc, d.logFile = nil, nil
+		return errors.New(csferr + err.Error())
 	}
 	return nil
 }
 
-func (d *PhantomJsDriver) Stop() error {
-	defer func() {
-		d.cmd = nil
-	}()
-	cmd := d.cmd
-	if cmd == nil {
-		return errors.New("stop failed: phantomJsdriver not running")
-	}
-	if cmd.Process == nil {
-		return errors.New("stop failed: process nil")
-	}
-	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+// Check that the phantomjs binary exists and is executable. A bare name
+// (e.g. "phantomjs") is resolved using PATH. Called by Start.
+func (d *PhantomJsDriver) VerifyBinary() error {
+	path, err := verifyBinary(d.path, "phantomjs")
+	if err != nil {
 		return err
 	}
-	if d.logFile != nil {
-		if err := d.logFile.Close(); err != nil {
-			return err
-		}
-	}
+	d.path = path
 	return nil
 }
 
-func (d *PhantomJsDriver) NewSession(desired, required Capabilities) (*Session, error) {
-	//id, capabs, err := d.newSession(desired, required)
-	//return &Session{id, capabs, d}, err
-	session, err := d.newSession(desired, required)
-	if err != nil {
-		return nil, err
-	}
-	session.wd = d
-	return session, nil
+// Stop phantomjs, if running, and start it again with the same configuration,
+// e.g. to recover from a browser crash. Sessions of the previous run are lost.
+// On failure the driver is left stopped.
+func (d *PhantomJsDriver) Restart() error {
+	return restartDriver(d, d.proc != nil)
 }
 
-func (d *PhantomJsDriver) Sessions() ([]Session, error) {
-	sessions, err := d.sessions()
-	if err != nil {
-		return nil, err
+func (d *PhantomJsDriver) Stop() error {
+	if d.proc == nil {
+		return errors.New("stop failed: phantomJsdriver not running")
+	}
+	defer func() {
+		d.proc = nil
+	}()
+	err := stopProcess(d.proc, d.StopTimeout)
+	if d.logFile != nil {
+		if cerr := d.logFile.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
 	}
-	for i := range sessions {
-		sessions[i].wd = d
+	if err != nil {
+		return errors.New("stop failed: " + err.Error())
 	}
-	return sessions, nil
+	return nil
 }
@@ -0,0 +1,226 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of lines of stderr of a driver process reported when it fails to start.
+const stderrTailLines = 20
+
+// tailBuffer is a Writer keeping only the last lines written.
+type tailBuffer struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func newTailBuffer(lines int) *tailBuffer {
+	return &tailBuffer{max: lines}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.partial = append(b.partial, p...)
+	for {
+		i := bytes.IndexByte(b.partial, '\n')
+		if i < 0 {
+			break
+		}
+		b.lines = append(b.lines, string(b.partial[:i]))
+		b.partial = b.partial[i+1:]
+	}
+	if len(b.lines) > b.max {
+		b.lines = append([]string(nil), b.lines[len(b.lines)-b.max:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := b.lines
+	if len(b.partial) > 0 {
+		lines = append(lines[:len(lines):len(lines)], string(b.partial))
+	}
+	if len(lines) > b.max {
+		lines = lines[len(lines)-b.max:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// a running driver process.
+type process struct {
+	cmd    *exec.Cmd
+	stderr *tailBuffer
+	// closed when the process exits, err is then the result of cmd.Wait
+	done chan struct{}
+	err  error
+}
+
+// start cmd sending its output to stdout and stderr. The tail of stderr is
+// kept to report startup failures.
+func startProcess(cmd *exec.Cmd, stdout, stderr io.Writer) (*process, error) {
+	p := &process{cmd: cmd, stderr: newTailBuffer(stderrTailLines), done: make(chan struct{})}
+	cmd.Stdout = stdout
+	cmd.Stderr = io.MultiWriter(stderr, p.stderr)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// reports if the process exited.
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait until the process listens on port. Fails immediately if the process
+// exits, with its exit status, or when timeout is up. In both cases the error
+// includes the tail of stderr.
+func (p *process) waitStart(port int, timeout time.Duration) error {
+	err := probePort(port, timeout, p.done)
+	if p.exited() {
+		status := "exit status 0"
+		if p.err != nil {
+			status = p.err.Error()
+		}
+		return p.withStderr(fmt.Errorf("process exited during startup (%s)", status))
+	}
+	if err != nil {
+		return p.withStderr(err)
+	}
+	return nil
+}
+
+// append the tail of stderr to err.
+func (p *process) withStderr(err error) error {
+	tail := p.stderr.String()
+	if tail == "" {
+		return err
+	}
+	return fmt.Errorf("%s, stderr:\n%s", err, tail)
+}
+
+// interrupt the process and wait up to timeout for it to exit, then kill it.
+// Returns an error if the process had to be killed. A timeout of 0 waits
+// forever.
+func stopProcess(p *process, timeout time.Duration) error {
+	if p.exited() {
+		return nil
+	}
+	if err := p.cmd.Process.Signal(os.Interrupt); err != nil {
+		// interrupt is not available on every platform (e.g. windows)
+		if err := p.cmd.Process.Kill(); err != nil && !p.exited() {
+			return err
+		}
+		<-p.done
+		return fmt.Errorf("process killed: interrupt failed: %s", err)
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-p.done:
+		return nil
+	case <-expired:
+	}
+	if err := p.cmd.Process.Kill(); err != nil && !p.exited() {
+		return err
+	}
+	<-p.done
+	return fmt.Errorf("process killed: did not exit within %s of interrupt", timeout)
+}
+
+// clean up after a failed start: stop p, if started, and close logFile, if
+// open, so that Start can be called again.
+func abortStart(p *process, logFile *os.File, timeout time.Duration) {
+	if p != nil {
+		stopProcess(p, timeout)
+	}
+	if logFile != nil {
+		logFile.Close()
+	}
+}
+
+// A MultiError collects the errors of several independent operations, e.g. of
+// the drivers stopped by StopAll.
+type MultiError struct {
+	errs []error
+}
+
+// The collected errors, in the order they happened.
+func (e *MultiError) Errors() []error {
+	return e.errs
+}
+
+// Unwrap lets errors.Is and errors.As (Go 1.20 and later) match any of the
+// collected errors.
+func (e *MultiError) Unwrap() []error {
+	return e.errs
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Stop all drivers, e.g. in the teardown of a test suite. A driver failing to
+// stop doesn't prevent the others from being stopped, each waits up to its own
+// StopTimeout. If any driver fails a *MultiError is returned, with an error
+// per failed driver that wraps the error of its Stop and is prefixed with the
+// driver type (e.g. "*webdriver.ChromeDriver: stop failed: ...").
+func StopAll(drivers ...WebDriver) error {
+	var errs []error
+	for _, d := range drivers {
+		if err := d.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("%T: %w", d, err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &MultiError{errs}
+}
+
+// stop d if running is true, then start it again. A failed start leaves no
+// process behind (e.g. one that didn't listen on its port in time).
+func restartDriver(d WebDriver, running bool) error {
+	if running {
+		if err := d.Stop(); err != nil {
+			return errors.New("restart failed: " + err.Error())
+		}
+	}
+	if err := d.Start(); err != nil {
+		return errors.New("restart failed: " + err.Error())
+	}
+	return nil
+}
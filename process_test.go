@@ -0,0 +1,195 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailBuffer(t *testing.T) {
+	b := newTailBuffer(2)
+	fmt.Fprint(b, "one\ntwo\nthr")
+	fmt.Fprint(b, "ee\nfour")
+	if got := b.String(); got != "three\nfour" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestProcessExitDuringStartup(t *testing.T) {
+	port, err := GetFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("sh", "-c", "echo starting >&2; echo bad flag >&2; exit 3")
+	p, err := startProcess(cmd, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err = p.waitStart(port, 20*time.Second)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("exit not detected until timeout")
+	}
+	for _, s := range []string{"exit status 3", "starting\nbad flag"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("%q missing in error: %s", s, err)
+		}
+	}
+}
+
+func TestStartAfterFailedStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "failedstart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	exits := script("exits", "echo bad flag >&2; exit 3")
+	hangs := script("hangs", "exec sleep 30")
+	check := func(name string, start func() error, holding func() bool) {
+		for attempt := 1; attempt <= 2; attempt++ {
+			err := start()
+			if err == nil {
+				t.Fatalf("%s: attempt %d: expected an error", name, attempt)
+			}
+			if strings.Contains(err.Error(), "already running") {
+				t.Fatalf("%s: attempt %d: %s", name, attempt, err)
+			}
+			if holding() {
+				t.Errorf("%s: attempt %d: process or log file left after the failure", name, attempt)
+			}
+		}
+	}
+	for _, path := range []string{exits, hangs} {
+		chrome := NewChromeDriver(path)
+		chrome.StartTimeout = 300 * time.Millisecond
+		chrome.LogPath = filepath.Join(dir, "chromedriver.log")
+		chrome.LogFile = filepath.Join(dir, "chrome.out")
+		check(path, chrome.Start, func() bool { return chrome.proc != nil || chrome.logFile != nil })
+		phantom := NewPhantomJsDriver(path)
+		phantom.StartTimeout = 300 * time.Millisecond
+		phantom.LogFile = filepath.Join(dir, "phantom.out")
+		check(path, phantom.Start, func() bool { return phantom.proc != nil || phantom.logFile != nil })
+	}
+}
+
+func TestStopProcess(t *testing.T) {
+	start := func(script string) *process {
+		p, err := startProcess(exec.Command("sh", "-c", script), ioutil.Discard, ioutil.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	if err := stopProcess(start("exec sleep 30"), time.Second); err != nil {
+		t.Errorf("clean stop failed: %s", err)
+	}
+	p := start("trap '' INT; exec sleep 30")
+	// give sh time to install the trap
+	time.Sleep(100 * time.Millisecond)
+	err := stopProcess(p, 200*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "killed") {
+		t.Errorf("expected a kill error, got %v", err)
+	}
+	if !p.exited() {
+		t.Error("process still running")
+	}
+}
+
+func TestStopAll(t *testing.T) {
+	running := NewPhantomJsDriver("phantomjs")
+	p, err := startProcess(exec.Command("sleep", "30"), ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running.proc = p
+	err = StopAll(NewChromeDriver("chromedriver"), running, NewFirefoxDriver("firefox", "webdriver.xpi"))
+	if err == nil {
+		t.Fatal("expected errors for the drivers not running")
+	}
+	merr, ok := err.(*MultiError)
+	if !ok {
+		t.Fatalf("expected a *MultiError, got %T", err)
+	}
+	errs := merr.Errors()
+	if len(errs) != 2 || !strings.HasPrefix(errs[0].Error(), "*webdriver.ChromeDriver: ") || !strings.HasPrefix(errs[1].Error(), "*webdriver.FirefoxDriver: ") {
+		t.Errorf("unexpected errors: %q", errs)
+	}
+	if !strings.Contains(err.Error(), "chromedriver not running; ") || !strings.Contains(err.Error(), "firefoxdriver not running") {
+		t.Errorf("missing errors: %s", err)
+	}
+	if !p.exited() {
+		t.Error("running driver not stopped")
+	}
+}
+
+func TestRestartDriver(t *testing.T) {
+	d := NewPhantomJsDriver("/nonexistent/phantomjs")
+	p, err := startProcess(exec.Command("sleep", "30"), ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.proc = p
+	err = d.Restart()
+	if err == nil || !strings.HasPrefix(err.Error(), "restart failed: ") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !p.exited() {
+		t.Error("previous process still running")
+	}
+	if d.proc != nil {
+		t.Error("driver not left stopped")
+	}
+}
+
+func TestChromeNewSessionRetry(t *testing.T) {
+	var attempts int
+	message := "unknown error: chrome not reachable"
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session" {
+			attempts++
+			if attempts < 3 {
+				return &CommandError{StatusCode: SessionNotCreatedException, Message: message}
+			}
+		}
+		return nil
+	})
+	defer func(d time.Duration) { newSessionBackoff = d }(newSessionBackoff)
+	newSessionBackoff = time.Millisecond
+	defer func(l Logger) { WarningLogger = l }(WarningLogger)
+	WarningLogger = nil
+	d := NewChromeDriver("chromedriver")
+	d.url = f.URL
+	if _, err := d.NewSession(nil, nil); err != nil || attempts != 3 {
+		t.Fatalf("%d attempts: %v", attempts, err)
+	}
+	attempts = 0
+	message = "session not created: This version of ChromeDriver only supports Chrome version 99"
+	if _, err := d.NewSession(nil, nil); err == nil || attempts != 1 {
+		t.Errorf("permanent error retried: %d attempts, %v", attempts, err)
+	}
+	attempts = 0
+	message = "unknown error: chrome not reachable"
+	var wd WebDriver = d
+	if err := wd.WithSession(nil, func(*Session) error { return nil }); err != nil || attempts != 3 {
+		t.Errorf("WithSession without retries: %d attempts, %v", attempts, err)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Values of Proxy.ProxyType.
+const (
+	ProxyDirect     = "direct"
+	ProxyManual     = "manual"
+	ProxyPAC        = "pac"
+	ProxyAutodetect = "autodetect"
+	ProxySystem     = "system"
+)
+
+// The "proxy" capability.
+type Proxy struct {
+	ProxyType          string `json:"proxyType"`
+	ProxyAutoconfigUrl string `json:"proxyAutoconfigUrl,omitempty"`
+	FtpProxy           string `json:"ftpProxy,omitempty"`
+	HttpProxy          string `json:"httpProxy,omitempty"`
+	SslProxy           string `json:"sslProxy,omitempty"`
+	SocksProxy         string `json:"socksProxy,omitempty"`
+	SocksUsername      string `json:"socksUsername,omitempty"`
+	SocksPassword      string `json:"socksPassword,omitempty"`
+	// Hosts not proxied. Decoded from the comma separated string of the JSON
+	// Wire Protocol or the list of the W3C protocol.
+	NoProxy hostList `json:"noProxy,omitempty"`
+}
+
+// a list of hosts, encoded as a JSON list.
+type hostList []string
+
+func (l *hostList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*l = list
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*l = nil
+	for _, host := range strings.Split(s, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			*l = append(*l, host)
+		}
+	}
+	return nil
+}
+
+// ErrNoProxy is returned by Session.Proxy if the session has no proxy capability.
+var ErrNoProxy = errors.New("no proxy configured")
+
+// Get the proxy configuration negotiated with the driver, to check that it
+// accepted the one requested instead of ignoring it. ErrNoProxy and a zero
+// Proxy are returned if the session capabilities have no proxy, or one without
+// proxyType (W3C drivers report "proxy": {} when no proxy is configured).
+func (s Session) Proxy() (Proxy, error) {
+	raw, ok := s.Capabilities["proxy"]
+	if !ok || raw == nil {
+		return Proxy{}, ErrNoProxy
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return Proxy{}, err
+	}
+	var proxy Proxy
+	if err := json.Unmarshal(data, &proxy); err != nil {
+		return Proxy{}, errors.New("proxy failed: invalid proxy capability: " + err.Error())
+	}
+	if proxy.ProxyType == "" {
+		return Proxy{}, ErrNoProxy
+	}
+	return proxy, nil
+}
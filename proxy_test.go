@@ -0,0 +1,42 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSessionProxy(t *testing.T) {
+	tests := []string{
+		`{"proxy":{"proxyType":"manual","httpProxy":"proxy:3128","noProxy":"localhost, 127.0.0.1"}}`,
+		`{"proxy":{"proxyType":"manual","httpProxy":"proxy:3128","noProxy":["localhost","127.0.0.1"]}}`,
+	}
+	want := Proxy{ProxyType: ProxyManual, HttpProxy: "proxy:3128", NoProxy: hostList{"localhost", "127.0.0.1"}}
+	for _, data := range tests {
+		var s Session
+		if err := json.Unmarshal([]byte(data), &s.Capabilities); err != nil {
+			t.Fatal(err)
+		}
+		proxy, err := s.Proxy()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(proxy, want) {
+			t.Errorf("got %+v, want %+v", proxy, want)
+		}
+	}
+	for _, caps := range []Capabilities{
+		{},
+		{"proxy": nil},
+		{"proxy": map[string]interface{}{}},
+		{"proxy": map[string]interface{}{"proxyType": ""}},
+	} {
+		if _, err := (Session{Capabilities: caps}).Proxy(); err != ErrNoProxy {
+			t.Errorf("%v: expected ErrNoProxy, got %v", caps, err)
+		}
+	}
+}
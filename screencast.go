@@ -0,0 +1,126 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Options of a screencast, zero values leave the choice to Chrome.
+type ScreencastOptions struct {
+	// JPEG compression quality, from 0 to 100.
+	Quality int
+	// Maximum width and height of the frames, frames are scaled down to fit.
+	MaxWidth  int
+	MaxHeight int
+	// Capture only one frame every EveryNthFrame.
+	EveryNthFrame int
+}
+
+type screencast struct {
+	conn *cdpConn
+
+	mu  sync.Mutex
+	err error
+}
+
+// Record the current page with CDP Page.startScreencast and write the frames
+// to w until StopScreencast is called.
+//
+// Frames are JPEG images written one after the other with nothing in between:
+// the result is a raw MJPEG stream (e.g. play it with "ffplay -f mjpeg" or
+// convert it with "ffmpeg -f mjpeg -i cast.mjpeg cast.mp4"). Chrome sends a
+// frame only when the page changes, so the stream has no fixed frame rate.
+//
+// w is written from another goroutine. Chrome only, ErrUnsupportedCommand is
+// returned for other browsers.
+func (s Session) StartScreencast(w io.Writer, opts ScreencastOptions) error {
+	st, err := s.sharedState()
+	if err != nil {
+		return errors.New("start screencast failed: " + err.Error())
+	}
+	st.cdpMu.Lock()
+	defer st.cdpMu.Unlock()
+	if st.screencast != nil {
+		return errors.New("start screencast failed: screencast already running")
+	}
+	conn, err := s.cdpConnect()
+	if err != nil {
+		return err
+	}
+	sc := &screencast{conn: conn}
+	conn.on("Page.screencastFrame", func(raw json.RawMessage) {
+		var frame struct {
+			Data      string `json:"data"`
+			SessionId int    `json:"sessionId"`
+		}
+		err := json.Unmarshal(raw, &frame)
+		if err == nil {
+			err = conn.send("Page.screencastFrameAck", params{"sessionId": frame.SessionId})
+		}
+		var jpeg []byte
+		if err == nil {
+			jpeg, err = base64.StdEncoding.DecodeString(frame.Data)
+		}
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+		if sc.err != nil {
+			return
+		}
+		if err == nil {
+			_, err = w.Write(jpeg)
+		}
+		sc.err = err
+	})
+	p := params{"format": "jpeg"}
+	if opts.Quality != 0 {
+		p["quality"] = opts.Quality
+	}
+	if opts.MaxWidth != 0 {
+		p["maxWidth"] = opts.MaxWidth
+	}
+	if opts.MaxHeight != 0 {
+		p["maxHeight"] = opts.MaxHeight
+	}
+	if opts.EveryNthFrame != 0 {
+		p["everyNthFrame"] = opts.EveryNthFrame
+	}
+	if err := conn.call("Page.startScreencast", p, nil); err != nil {
+		conn.Close()
+		return err
+	}
+	st.screencast = sc
+	return nil
+}
+
+// Stop the screencast started with StartScreencast, also through another copy
+// of the session. The returned error reports also failures writing the frames.
+func (s Session) StopScreencast() error {
+	st, err := s.sharedState()
+	if err != nil {
+		return errors.New("stop screencast failed: " + err.Error())
+	}
+	st.cdpMu.Lock()
+	sc := st.screencast
+	st.screencast = nil
+	st.cdpMu.Unlock()
+	if sc == nil {
+		return errors.New("stop screencast failed: screencast not running")
+	}
+	err = sc.conn.call("Page.stopScreencast", nil, nil)
+	if cerr := sc.conn.Close(); err == nil {
+		err = cerr
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.err != nil {
+		return sc.err
+	}
+	return err
+}
@@ -0,0 +1,680 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+)
+
+// ErrScriptNull is returned by the typed ExecuteScript methods when the script
+// returns null or undefined.
+var ErrScriptNull = errors.New("script returned null")
+
+// ErrDisconnectedElements is returned by Session.ElementsInDocumentOrder when
+// the elements are not in the same document (e.g. in different frames, or one
+// of them detached or stale).
+var ErrDisconnectedElements = errors.New("elements not in the same document")
+
+// run a synchronous script and decode its return value into result.
+// result can be nil if the return value is not needed. If the script returns
+// null (or undefined) the value pointed by result is set to its zero value.
+func (s Session) executeScript(script string, args []interface{}, result interface{}) error {
+	if args == nil {
+		args = []interface{}{}
+	}
+	data, err := s.ExecuteScript(script, args)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	if isNull(data) {
+		if v := reflect.ValueOf(result); v.Kind() == reflect.Ptr && !v.IsNil() {
+			v.Elem().Set(reflect.Zero(v.Elem().Type()))
+		}
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return err
+	}
+	s.bindElements(reflect.ValueOf(result))
+	return nil
+}
+
+// Execute a script like ExecuteScript and decode its return value into result.
+// Element references are decoded into the fields of type WebElement,
+// *WebElement, []WebElement... wherever they are in result (nested structs,
+// slices, maps), and bound to the session, e.g. to scrape a table:
+//
+//	var table struct {
+//		Header *WebElement
+//		Rows   []struct{ Cells []WebElement }
+//	}
+//	err := session.Execute(script, nil, &table)
+//
+// If the script returns null the value pointed by result is set to its zero
+// value. result can be nil if the return value is not needed.
+func (s Session) Execute(script string, args []interface{}, result interface{}) error {
+	return s.executeScript(script, args, result)
+}
+
+var webElementType = reflect.TypeOf(WebElement{})
+
+// bind to s the WebElements reachable from v.
+func (s *Session) bindElements(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			s.bindElements(v.Elem())
+		}
+	case reflect.Struct:
+		if v.Type() == webElementType {
+			if v.CanAddr() {
+				v.Addr().Interface().(*WebElement).s = s
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				s.bindElements(v.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			s.bindElements(v.Index(i))
+		}
+	case reflect.Map:
+		// map values are not addressable, bind a copy and store it back
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			s.bindElements(value)
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+}
+
+func isNull(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) == 0 || string(data) == "null"
+}
+
+// run a script whose return value must be of the JSON type described by kind.
+func (s Session) executeScriptTyped(script string, args []interface{}, kind string, result interface{}) error {
+	if args == nil {
+		args = []interface{}{}
+	}
+	data, err := s.ExecuteScript(script, args)
+	if err != nil {
+		return err
+	}
+	if isNull(data) {
+		return ErrScriptNull
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("execute script failed: expected %s result, got %s", kind, data)
+	}
+	return nil
+}
+
+// Execute a script returning a boolean. ErrScriptNull is returned if the
+// script returns null or undefined, an error if it returns another type.
+func (s Session) ExecuteScriptBool(script string, args []interface{}) (bool, error) {
+	var v bool
+	err := s.executeScriptTyped(script, args, "bool", &v)
+	return v, err
+}
+
+// Execute a script returning a string, see ExecuteScriptBool.
+func (s Session) ExecuteScriptString(script string, args []interface{}) (string, error) {
+	var v string
+	err := s.executeScriptTyped(script, args, "string", &v)
+	return v, err
+}
+
+// Execute a script returning an integer number, see ExecuteScriptBool.
+func (s Session) ExecuteScriptInt(script string, args []interface{}) (int, error) {
+	var v int
+	err := s.executeScriptTyped(script, args, "integer", &v)
+	return v, err
+}
+
+// Read the script stored in the file at path and execute it like ExecuteScript,
+// decoding its return value into result (can be nil).
+func (s Session) ExecuteScriptFile(path string, args []interface{}, result interface{}) error {
+	script, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("execute script file failed: %w", err)
+	}
+	return s.executeScript(string(script), args, result)
+}
+
+// Read the script stored in the file at path, panics if the file can't be read.
+// Meant to initialize package level script variables:
+//
+//	var clickAll = webdriver.MustLoadScript("scripts/click_all.js")
+//
+// Scripts embedded with go:embed are already in memory, convert them with
+// string() instead.
+func MustLoadScript(path string) string {
+	script, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic("webdriver: load script failed: " + err.Error())
+	}
+	return string(script)
+}
+
+// run a script returning a DOM element (or null) and bind the result to s.
+// ErrNoSuchElement is returned if the script returns null.
+func (s Session) executeScriptElement(script string, args []interface{}) (WebElement, error) {
+	var elem *element
+	if err := s.executeScript(script, args, &elem); err != nil {
+		return WebElement{}, err
+	}
+	if elem == nil || elem.id() == "" {
+		return WebElement{}, ErrNoSuchElement
+	}
+	return WebElement{&s, elem.id()}, nil
+}
+
+const deepFindElementScript = `
+var find = function(root, selector) {
+	var el = root.querySelector(selector);
+	if (el) {
+		return el;
+	}
+	var all = root.querySelectorAll("*");
+	for (var i = 0; i < all.length; i++) {
+		if (all[i].shadowRoot) {
+			el = find(all[i].shadowRoot, selector);
+			if (el) {
+				return el;
+			}
+		}
+	}
+	return null;
+};
+return find(document, arguments[0]);`
+
+// Search for an element matching cssSelector in the document and, recursively,
+// in every shadow root attached to it. The first match found (document first,
+// then shadow roots in document order) is returned, ErrNoSuchElement if
+// nothing matches.
+//
+// The selector is matched inside a single tree at a time, it cannot span a
+// shadow boundary. Every element of every tree is visited until a match is
+// found, so on large pages this is much slower than FindElement.
+func (s Session) DeepFindElement(cssSelector string) (WebElement, error) {
+	return s.executeScriptElement(deepFindElementScript, []interface{}{cssSelector})
+}
+
+const sendKeysIMEScript = `
+var el = arguments[0], composition = arguments[1], result = arguments[2];
+if (typeof CompositionEvent === "undefined") {
+	return false;
+}
+el.focus();
+var fire = function(type, data) {
+	el.dispatchEvent(new CompositionEvent(type, {bubbles: true, cancelable: true, data: data}));
+};
+fire("compositionstart", "");
+fire("compositionupdate", composition);
+if ("value" in el) {
+	var start = el.selectionStart != null ? el.selectionStart : el.value.length;
+	var end = el.selectionEnd != null ? el.selectionEnd : el.value.length;
+	el.value = el.value.slice(0, start) + result + el.value.slice(end);
+} else {
+	el.textContent += result;
+}
+fire("compositionend", result);
+el.dispatchEvent(new InputEvent("input", {bubbles: true, data: result, inputType: "insertCompositionText"}));
+return true;`
+
+// Simulate an IME composition on the element: composition is the text shown
+// while composing and result the committed text (e.g. "nihon" and "日本").
+// compositionstart, compositionupdate, compositionend and input events are
+// dispatched and result is inserted at the cursor of inputs and textareas, or
+// appended to the text of other (contenteditable) elements.
+//
+// Events are synthetic (not trusted) and no real IME is involved. Returns
+// ErrUnsupportedCommand if the browser lacks CompositionEvent.
+func (e WebElement) SendKeysIME(composition, result string) error {
+	var ok bool
+	if err := e.s.executeScript(sendKeysIMEScript, []interface{}{e, composition, result}, &ok); err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnsupportedCommand
+	}
+	return nil
+}
+
+const viewportRectScript = `
+var r = arguments[0].getBoundingClientRect();
+return {
+	left: r.left, top: r.top, right: r.right, bottom: r.bottom,
+	width: window.innerWidth || document.documentElement.clientWidth,
+	height: window.innerHeight || document.documentElement.clientHeight
+};`
+
+// bounding box of an element relative to the viewport, and size of the viewport.
+type viewportRect struct {
+	Left, Top, Right, Bottom float64
+	Width, Height            float64
+}
+
+func (e WebElement) viewportRect() (viewportRect, error) {
+	var r viewportRect
+	err := e.s.executeScript(viewportRectScript, []interface{}{e}, &r)
+	return r, err
+}
+
+// The fraction (0.0 to 1.0) of the area of the element inside the viewport,
+// e.g. to assert that a lazy loaded image or an ad is at least half visible.
+// Elements entirely off-screen or without area return 0. Only the bounding box
+// is considered: elements hidden by CSS or covered by other elements still
+// count as visible.
+func (e WebElement) VisibleFraction() (float64, error) {
+	r, err := e.viewportRect()
+	if err != nil {
+		return 0, err
+	}
+	area := (r.Right - r.Left) * (r.Bottom - r.Top)
+	if area <= 0 {
+		return 0, nil
+	}
+	w := math.Min(r.Right, r.Width) - math.Max(r.Left, 0)
+	h := math.Min(r.Bottom, r.Height) - math.Max(r.Top, 0)
+	if w <= 0 || h <= 0 {
+		return 0, nil
+	}
+	return w * h / area, nil
+}
+
+// Report if the bounding box of the element is entirely inside the viewport.
+// Unlike IsDisplayed this is false for elements scrolled out of view.
+func (e WebElement) IsInViewport() (bool, error) {
+	r, err := e.viewportRect()
+	if err != nil {
+		return false, err
+	}
+	return r.Left >= 0 && r.Top >= 0 && r.Right <= r.Width && r.Bottom <= r.Height, nil
+}
+
+// Report if at least part of the bounding box of the element is inside the
+// viewport, e.g. for an element straddling the fold.
+func (e WebElement) PartiallyInViewport() (bool, error) {
+	r, err := e.viewportRect()
+	if err != nil {
+		return false, err
+	}
+	return r.Right > 0 && r.Bottom > 0 && r.Left < r.Width && r.Top < r.Height, nil
+}
+
+// Get the topmost element at the point x, y of the viewport, i.e. the element
+// a click there would hit. ErrNoSuchElement is returned if the point is outside
+// the viewport or hits nothing.
+func (s Session) ElementFromPoint(x, y int) (WebElement, error) {
+	return s.executeScriptElement("return document.elementFromPoint(arguments[0], arguments[1]);", []interface{}{x, y})
+}
+
+// Get the form the element belongs to, through its form property that also
+// follows the form attribute. ErrNoSuchElement is returned if the element is
+// not associated with a form.
+func (e WebElement) OwningForm() (WebElement, error) {
+	return e.s.executeScriptElement("return arguments[0].form || null;", []interface{}{e})
+}
+
+const dispatchEventScript = `
+var el = arguments[0], type = arguments[1], detail = arguments[2] || {};
+var classes = {
+	click: "MouseEvent", dblclick: "MouseEvent", mousedown: "MouseEvent", mouseup: "MouseEvent",
+	mouseover: "MouseEvent", mouseout: "MouseEvent", mousemove: "MouseEvent", contextmenu: "MouseEvent",
+	keydown: "KeyboardEvent", keyup: "KeyboardEvent", keypress: "KeyboardEvent",
+	focus: "FocusEvent", blur: "FocusEvent", focusin: "FocusEvent", focusout: "FocusEvent",
+	input: "InputEvent", change: "Event", submit: "Event", reset: "Event"
+};
+var name = classes[type];
+var event;
+if (name) {
+	var init = {bubbles: true, cancelable: true};
+	for (var k in detail) {
+		init[k] = detail[k];
+	}
+	var cls = window[name] || Event;
+	event = new cls(type, init);
+} else {
+	event = new CustomEvent(type, {bubbles: true, cancelable: true, detail: detail});
+}
+el.dispatchEvent(event);`
+
+// Dispatch a synthetic event of type eventType on the element, with bubbles and
+// cancelable set, e.g. to fire the input or change events that SendKeys doesn't
+// always trigger on framework managed inputs. The event class depends on the
+// type:
+//
+//	click, dblclick, mousedown, mouseup, mouseover, mouseout, mousemove, contextmenu   MouseEvent
+//	keydown, keyup, keypress                                                          KeyboardEvent
+//	focus, blur, focusin, focusout                                                    FocusEvent
+//	input                                                                             InputEvent
+//	change, submit, reset                                                             Event
+//	any other type                                                                    CustomEvent
+//
+// For the known types the entries of detail are set in the event init (e.g.
+// {"key": "Enter"} for a KeyboardEvent), for a CustomEvent detail is its
+// detail property. detail can be nil. Events have isTrusted false.
+func (e WebElement) DispatchEvent(eventType string, detail map[string]interface{}) error {
+	return e.s.executeScript(dispatchEventScript, []interface{}{e, eventType, detail}, nil)
+}
+
+// Set the attribute name of the element to value with a script, e.g. to unhide
+// a native file input or set a data attribute. WebDriver has no command for
+// this on purpose: it changes the page in a way a user can't, use it only as
+// an escape hatch in tests. Stale element errors are returned as is.
+func (e WebElement) SetAttribute(name, value string) error {
+	return e.s.executeScript("arguments[0].setAttribute(arguments[1], arguments[2]);", []interface{}{e, name, value}, nil)
+}
+
+// Remove the attribute name of the element with a script, see SetAttribute.
+func (e WebElement) RemoveAttribute(name string) error {
+	return e.s.executeScript("arguments[0].removeAttribute(arguments[1]);", []interface{}{e, name}, nil)
+}
+
+const elementStateScript = `
+var el = arguments[0];
+if (!el || !el.isConnected) {
+	return null;
+}
+var style = window.getComputedStyle(el);
+var r = el.getBoundingClientRect();
+var x = window.pageXOffset, y = window.pageYOffset;
+return {
+	displayed: style.display !== "none" && style.visibility !== "hidden" && style.visibility !== "collapse" &&
+		el.getClientRects().length > 0,
+	enabled: !el.matches(":disabled"),
+	selected: !!(el.checked || el.selected),
+	rect: {x: r.left + x, y: r.top + y, width: r.width, height: r.height}
+};`
+
+// The state of an element read by WebElement.State.
+type ElementState struct {
+	Displayed bool
+	Enabled   bool
+	Selected  bool
+	Rect      Rect
+}
+
+// Read the displayed, enabled and selected state and the rect of the element
+// with a single script, instead of a command for each. Displayed is computed
+// by the script (display, visibility and layout boxes) and can differ from
+// IsDisplayed for edge cases like elements with zero opacity or covered ones.
+// If the element was removed from the page a stale element reference error is
+// returned.
+func (e WebElement) State() (ElementState, error) {
+	var state *ElementState
+	if err := e.s.executeScript(elementStateScript, []interface{}{e}, &state); err != nil {
+		return ElementState{}, err
+	}
+	if state == nil {
+		return ElementState{}, &CommandError{StatusCode: StaleElementReference, Message: "element is not attached to the page document", Label: e.s.Label()}
+	}
+	return *state, nil
+}
+
+// ErrFrameURLBlocked is returned by FrameURL when the browser doesn't let the
+// script read the location of the frame.
+var ErrFrameURLBlocked = errors.New("frame url not readable")
+
+// Get the url of the current frame, as seen by window.location.href in its
+// context, while GetUrl returns the url of the top-level page whatever the
+// focused frame. The script runs inside the frame, so cross-origin frames can
+// usually be read, but sandboxed or opaque-origin frames can block it: then
+// the error wraps ErrFrameURLBlocked.
+func (s Session) FrameURL() (string, error) {
+	var href string
+	err := s.executeScript("return window.location.href;", nil, &href)
+	if statusCode(err) == JavaScriptError {
+		return "", fmt.Errorf("%w: %s", ErrFrameURLBlocked, err)
+	}
+	return href, err
+}
+
+const html5DragScript = `
+var source = arguments[0], target = arguments[1];
+var data = new DataTransfer();
+var fire = function(el, type) {
+	var r = el.getBoundingClientRect();
+	var event = new DragEvent(type, {
+		bubbles: true, cancelable: true, dataTransfer: data,
+		clientX: r.left + r.width / 2, clientY: r.top + r.height / 2
+	});
+	el.dispatchEvent(event);
+};
+fire(source, "dragstart");
+fire(target, "dragenter");
+fire(target, "dragover");
+fire(target, "drop");
+fire(source, "dragend");`
+
+// Drag the element onto target dispatching the HTML5 drag and drop events
+// (dragstart, dragenter, dragover, drop, dragend) with a shared DataTransfer.
+// Browsers don't start a native drag from the synthetic mouse events of
+// DragTo, so libraries built on the HTML5 drag and drop API need this. Events
+// are synthetic (not trusted) and no pointer moves.
+func (e WebElement) HTML5DragTo(target WebElement) error {
+	return e.s.executeScript(html5DragScript, []interface{}{e, target}, nil)
+}
+
+const selectOptionsScript = `
+var el = arguments[0], field = arguments[1];
+if (el.tagName.toLowerCase() !== "select") {
+	return null;
+}
+var result = [];
+for (var i = 0; i < el.options.length; i++) {
+	result.push(field === "text" ? el.options[i].text.trim() : el.options[i].value);
+}
+return result;`
+
+// the text or value of the options of a select element.
+func (e WebElement) selectOptions(field string) ([]string, error) {
+	var options *[]string
+	if err := e.s.executeScript(selectOptionsScript, []interface{}{e, field}, &options); err != nil {
+		return nil, err
+	}
+	if options == nil {
+		return nil, errors.New("select options failed: element is not a select")
+	}
+	return *options, nil
+}
+
+// Get the visible texts of all the options of a select element.
+func (e WebElement) OptionTexts() ([]string, error) {
+	return e.selectOptions("text")
+}
+
+// Get the values of all the options of a select element.
+func (e WebElement) OptionValues() ([]string, error) {
+	return e.selectOptions("value")
+}
+
+// Scroll the element into view with scrollIntoView({block: "nearest"}): every
+// scrollable ancestor, not just the window, is scrolled the least needed to
+// show it. Interaction commands only scroll the element into the viewport,
+// which is not enough for elements inside scrollable panels or virtualized
+// lists.
+func (e WebElement) ScrollIntoViewNearest() error {
+	return e.s.executeScript(`arguments[0].scrollIntoView({block: "nearest", inline: "nearest"});`, []interface{}{e}, nil)
+}
+
+const scrollContainerScript = `
+var el = arguments[0];
+var scrollable = function(node) {
+	var style = window.getComputedStyle(node);
+	return /(auto|scroll|overlay)/.test(style.overflowY + style.overflowX) &&
+		(node.scrollHeight > node.clientHeight || node.scrollWidth > node.clientWidth);
+};
+var container = el.parentElement;
+while (container && container !== document.body && container !== document.documentElement && !scrollable(container)) {
+	container = container.parentElement;
+}
+if (!container || container === document.body || container === document.documentElement) {
+	return false;
+}
+var c = container.getBoundingClientRect(), r = el.getBoundingClientRect();
+if (r.top < c.top) {
+	container.scrollTop -= c.top - r.top;
+} else if (r.bottom > c.bottom) {
+	container.scrollTop += Math.min(r.bottom - c.bottom, r.top - c.top);
+}
+if (r.left < c.left) {
+	container.scrollLeft -= c.left - r.left;
+} else if (r.right > c.right) {
+	container.scrollLeft += Math.min(r.right - c.right, r.left - c.left);
+}
+return true;`
+
+// Scroll the nearest scrollable ancestor of el, without moving the window, so
+// that el is visible in it. An error is returned if el is not in a scrollable
+// container. See ScrollIntoViewNearest to scroll all the ancestors at once.
+func (s Session) ScrollContainerTo(el WebElement) error {
+	var found bool
+	if err := s.executeScript(scrollContainerScript, []interface{}{el}, &found); err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("scroll container failed: element not in a scrollable container")
+	}
+	return nil
+}
+
+// Get the textContent, trimmed, of all the elements matching cssSelector with
+// a single script, instead of a command for each element. Only the data is
+// returned, not element handles.
+func (s Session) CollectText(cssSelector string) ([]string, error) {
+	script := `var r = []; document.querySelectorAll(arguments[0]).forEach(function(el) { r.push(el.textContent.trim()); }); return r;`
+	var texts []string
+	err := s.executeScript(script, []interface{}{cssSelector}, &texts)
+	return texts, err
+}
+
+// Get the attribute attr of all the elements matching cssSelector with a single
+// script, "" for elements without it. See CollectText.
+func (s Session) CollectAttribute(cssSelector, attr string) ([]string, error) {
+	script := `var attr = arguments[1], r = []; document.querySelectorAll(arguments[0]).forEach(function(el) { r.push(el.getAttribute(attr) || ""); }); return r;`
+	var values []string
+	err := s.executeScript(script, []interface{}{cssSelector, attr}, &values)
+	return values, err
+}
+
+const brokenImagesScript = `var broken = [];
+var images = document.images;
+for (var i = 0; i < images.length; i++) {
+	var img = images[i];
+	var src = img.currentSrc || img.src;
+	if (src && img.complete && img.naturalWidth === 0) {
+		broken.push(src);
+	}
+}
+return broken;`
+
+// Return the urls of the images (<img> elements) of the current page that
+// failed to load, an empty slice if all loaded, e.g. as a smoke test after a
+// deploy. Only the images currently in the document are checked; images still
+// loading, or lazy ones not loaded yet, are not reported, nor are CSS
+// background images.
+func (s Session) BrokenImages() ([]string, error) {
+	broken := []string{}
+	if err := s.executeScriptTyped(brokenImagesScript, nil, "array", &broken); err != nil {
+		return nil, err
+	}
+	return broken, nil
+}
+
+const documentOrderScript = `var a = arguments[0], b = arguments[1];
+if (a === b) return 0;
+var pos = a.compareDocumentPosition(b);
+if (pos & Node.DOCUMENT_POSITION_DISCONNECTED) return null;
+return pos & Node.DOCUMENT_POSITION_FOLLOWING ? -1 : 1;`
+
+// Compare the position of a and b in the document: -1 if a comes before b, 1
+// if after, 0 if they are the same element. An ancestor comes before its
+// descendants. E.g. to check that an error message is before the submit
+// button in the reading order, regardless of the layout. The error matches
+// ErrDisconnectedElements if the elements are not in the same document.
+func (s Session) ElementsInDocumentOrder(a, b WebElement) (int, error) {
+	order, err := s.ExecuteScriptInt(documentOrderScript, []interface{}{a, b})
+	if errors.Is(err, ErrScriptNull) {
+		return 0, ErrDisconnectedElements
+	}
+	if isElementGone(err) {
+		// an element of another frame is unknown in the current one
+		return 0, fmt.Errorf("%w: %s", ErrDisconnectedElements, err)
+	}
+	return order, err
+}
+
+// Reports if the document of the current window has the focus
+// (document.hasFocus()), i.e. if the browser and the operating system consider
+// it the target of keyboard input, e.g. to check that the focus followed
+// FocusOnWindow or that a popup didn't steal it. Headless browsers don't have
+// a real window manager and report it inconsistently: usually true for every
+// window, whatever was switched to.
+func (s Session) HasFocus() (bool, error) {
+	return s.ExecuteScriptBool("return document.hasFocus();", nil)
+}
+
+const tableDataScript = `var table = arguments[0];
+if (table.tagName.toLowerCase() !== "table") return null;
+var grid = [], spans = [];
+for (var r = 0; r < table.rows.length; r++) {
+	var row = grid[r] = grid[r] || [];
+	var cells = table.rows[r].cells;
+	var c = 0;
+	for (var i = 0; i < cells.length; i++) {
+		while (row[c] !== undefined) c++;
+		var text = cells[i].textContent.replace(/\s+/g, " ").trim();
+		var rowSpan = Math.max(cells[i].rowSpan, 1), colSpan = Math.max(cells[i].colSpan, 1);
+		// rowspan="0" spans the remaining rows of the table
+		if (cells[i].rowSpan === 0) rowSpan = table.rows.length - r;
+		for (var dr = 0; dr < rowSpan && r + dr < table.rows.length; dr++) {
+			var target = grid[r + dr] = grid[r + dr] || [];
+			for (var dc = 0; dc < colSpan; dc++) target[c + dc] = text;
+		}
+		c += colSpan;
+	}
+}
+for (var r = 0; r < grid.length; r++) {
+	for (var c = 0; c < grid[r].length; c++) {
+		if (grid[r][c] === undefined) grid[r][c] = "";
+	}
+}
+return grid;`
+
+// Read the text of the cells of a table element in one call, row by row as
+// table.rows orders them: the rows of thead first, then tbody and those of
+// tfoot last. The text is the textContent of the cell with collapsed
+// whitespace. Spanning cells are repeated in every row and column they cover
+// (colspan and rowspan), so that each row has a value for every column it
+// spans; holes of irregular tables are "". Nested tables are part of the text
+// of their cell.
+func (e WebElement) TableData() ([][]string, error) {
+	var rows *[][]string
+	if err := e.s.executeScript(tableDataScript, []interface{}{e}, &rows); err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		return nil, errors.New("table data failed: element is not a table")
+	}
+	return *rows, nil
+}
@@ -0,0 +1,317 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExecuteScriptTyped(t *testing.T) {
+	var value interface{}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			return value
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value = 42
+	if n, err := session.ExecuteScriptInt("return 42;", nil); err != nil || n != 42 {
+		t.Errorf("ExecuteScriptInt: %d, %v", n, err)
+	}
+	value = "42"
+	if _, err := session.ExecuteScriptInt("return '42';", nil); err == nil || errors.Is(err, ErrScriptNull) {
+		t.Errorf("mismatched type not reported: %v", err)
+	}
+	value = nil
+	if _, err := session.ExecuteScriptBool("return null;", nil); !errors.Is(err, ErrScriptNull) {
+		t.Errorf("expected ErrScriptNull, got %v", err)
+	}
+	result := struct{ A int }{A: 1}
+	if err := session.executeScript("return null;", nil, &result); err != nil || result.A != 0 {
+		t.Errorf("null not decoded as zero value: %+v, %v", result, err)
+	}
+}
+
+func TestViewport(t *testing.T) {
+	var rect map[string]interface{}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			return rect
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := WebElement{session, "1"}
+	tests := []struct {
+		top, bottom float64
+		in, partial bool
+		fraction    float64
+	}{
+		{100, 200, true, true, 1},
+		{700, 900, false, true, 0.5},
+		{900, 1000, false, false, 0},
+	}
+	for _, test := range tests {
+		rect = map[string]interface{}{"left": 0, "right": 100, "top": test.top, "bottom": test.bottom, "width": 1024, "height": 800}
+		in, err := el.IsInViewport()
+		if err != nil || in != test.in {
+			t.Errorf("%v: IsInViewport %v, %v", test, in, err)
+		}
+		partial, err := el.PartiallyInViewport()
+		if err != nil || partial != test.partial {
+			t.Errorf("%v: PartiallyInViewport %v, %v", test, partial, err)
+		}
+		fraction, err := el.VisibleFraction()
+		if err != nil || fraction != test.fraction {
+			t.Errorf("%v: VisibleFraction %v, %v", test, fraction, err)
+		}
+	}
+}
+
+func TestExecuteElementsInStruct(t *testing.T) {
+	ref := func(id string) map[string]string { return map[string]string{"ELEMENT": id} }
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			return map[string]interface{}{
+				"title":  "Users",
+				"header": ref("h"),
+				"rows": []interface{}{
+					map[string]interface{}{"name": "alice", "cells": []interface{}{ref("r1c1"), ref("r1c2")}},
+					map[string]interface{}{"name": "bob", "cells": []interface{}{ref("r2c1"), ref("r2c2")}},
+				},
+				"byName": map[string]interface{}{"alice": ref("r1c1")},
+				"footer": nil,
+			}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		Name  string
+		Cells []*WebElement
+	}
+	var table struct {
+		Title  string
+		Header *WebElement
+		Rows   []row
+		ByName map[string]WebElement
+		Footer *WebElement
+	}
+	if err := session.Execute("return scrape();", nil, &table); err != nil {
+		t.Fatal(err)
+	}
+	if table.Title != "Users" || table.Header == nil || table.Header.id != "h" || table.Footer != nil {
+		t.Fatalf("unexpected result: %+v", table)
+	}
+	if len(table.Rows) != 2 || table.Rows[1].Name != "bob" || len(table.Rows[1].Cells) != 2 || table.Rows[1].Cells[1].id != "r2c2" {
+		t.Fatalf("unexpected rows: %+v", table.Rows)
+	}
+	for _, el := range []WebElement{*table.Header, *table.Rows[0].Cells[0], *table.Rows[1].Cells[1], table.ByName["alice"]} {
+		if el.s == nil {
+			t.Fatalf("element %s not bound to the session", el.id)
+		}
+		if err := el.Click(); err != nil {
+			t.Fatal(err)
+		}
+		if r := f.last(); r.Path != "/session/fake-session/element/"+el.id+"/click" {
+			t.Errorf("unexpected request: %+v", r)
+		}
+	}
+}
+
+func TestElementState(t *testing.T) {
+	var state interface{}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			return state
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := WebElement{session, "1"}
+	state = map[string]interface{}{
+		"displayed": true, "enabled": false, "selected": true,
+		"rect": map[string]interface{}{"x": 10, "y": 20.5, "width": 100, "height": 30},
+	}
+	got, err := el.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ElementState{Displayed: true, Enabled: false, Selected: true, Rect: Rect{10, 20.5, 100, 30}}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	state = nil
+	if _, err := el.State(); !isElementGone(err) {
+		t.Errorf("expected a stale element error, got %v", err)
+	}
+}
+
+func TestFrameURL(t *testing.T) {
+	blocked := false
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			if blocked {
+				return &CommandError{StatusCode: JavaScriptError, Message: "SecurityError"}
+			}
+			return "http://frame.example/widget"
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, err := session.FrameURL(); err != nil || u != "http://frame.example/widget" {
+		t.Errorf("got %q, %v", u, err)
+	}
+	blocked = true
+	if _, err := session.FrameURL(); !errors.Is(err, ErrFrameURLBlocked) {
+		t.Errorf("expected ErrFrameURLBlocked, got %v", err)
+	}
+}
+
+func TestSelectOptions(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			args := r.Body["args"].([]interface{})
+			if args[0].(map[string]interface{})["ELEMENT"] != "select" {
+				return nil
+			}
+			if args[1] == "text" {
+				return []string{"Red", "Green"}
+			}
+			return []string{"r", "g"}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sel := WebElement{session, "select"}
+	if texts, err := sel.OptionTexts(); err != nil || len(texts) != 2 || texts[1] != "Green" {
+		t.Errorf("OptionTexts: %v, %v", texts, err)
+	}
+	if values, err := sel.OptionValues(); err != nil || len(values) != 2 || values[0] != "r" {
+		t.Errorf("OptionValues: %v, %v", values, err)
+	}
+	if _, err := (WebElement{session, "div"}).OptionTexts(); err == nil {
+		t.Error("expected an error for an element that is not a select")
+	}
+}
+
+func TestBrokenImages(t *testing.T) {
+	var images []string
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			return images
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images = []string{}
+	if broken, err := session.BrokenImages(); err != nil || broken == nil || len(broken) != 0 {
+		t.Errorf("got %q, %v", broken, err)
+	}
+	images = []string{"http://example.com/logo.png"}
+	if broken, err := session.BrokenImages(); err != nil || len(broken) != 1 || broken[0] != images[0] {
+		t.Errorf("got %q, %v", broken, err)
+	}
+}
+
+func TestElementsInDocumentOrder(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path != "/session/fake-session/execute" {
+			return nil
+		}
+		args := r.Body["args"].([]interface{})
+		a, b := args[0].(map[string]interface{})["ELEMENT"], args[1].(map[string]interface{})["ELEMENT"]
+		switch {
+		case a == "framed" || b == "framed":
+			return &CommandError{StatusCode: StaleElementReference, Message: "stale element reference"}
+		case a == "detached" || b == "detached":
+			return nil
+		case a == "error":
+			return -1
+		}
+		return 1
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el := func(id string) WebElement { return WebElement{session, id} }
+	if order, err := session.ElementsInDocumentOrder(el("error"), el("submit")); err != nil || order != -1 {
+		t.Errorf("got %d, %v", order, err)
+	}
+	for _, id := range []string{"framed", "detached"} {
+		if _, err := session.ElementsInDocumentOrder(el("submit"), el(id)); !errors.Is(err, ErrDisconnectedElements) {
+			t.Errorf("%s: expected ErrDisconnectedElements, got %v", id, err)
+		}
+	}
+}
+
+func TestHasFocus(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/execute" {
+			return false
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if focus, err := session.HasFocus(); err != nil || focus {
+		t.Errorf("got %v, %v", focus, err)
+	}
+	if r := f.last(); r.Body["script"] != "return document.hasFocus();" {
+		t.Errorf("unexpected request: %+v", r)
+	}
+}
+
+func TestTableData(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path != "/session/fake-session/execute" {
+			return nil
+		}
+		if r.Body["args"].([]interface{})[0].(map[string]interface{})["ELEMENT"] == "div" {
+			return nil
+		}
+		return [][]string{{"Name", "Qty"}, {"Pen", "2"}}
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := WebElement{session, "table"}.TableData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][0] != "Pen" || rows[1][1] != "2" {
+		t.Errorf("unexpected rows: %q", rows)
+	}
+	if _, err := (WebElement{session, "div"}).TableData(); err == nil {
+		t.Error("expected an error for a div")
+	}
+}
@@ -0,0 +1,649 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// A StaticDoc is a snapshot of the HTML of a page, queried with CSS selectors
+// without round trips to the browser. It is much faster than live elements for
+// bulk read-only assertions (e.g. checking every row of a large table), but it
+// is not the DOM: it doesn't reflect the changes made after it was taken, and
+// it is parsed by a simple, lenient parser that doesn't implement every error
+// recovery rule of browsers.
+type StaticDoc struct {
+	root *StaticElement
+}
+
+// An element of a StaticDoc.
+type StaticElement struct {
+	// lowercase tag name, empty for text nodes
+	Tag   string
+	Attrs map[string]string
+
+	text     string
+	parent   *StaticElement
+	children []*StaticElement
+}
+
+// Fetch the source of the current page (see Session.Source) and parse it into
+// a StaticDoc.
+func (s Session) StaticDocument() (*StaticDoc, error) {
+	source, err := s.Source()
+	if err != nil {
+		return nil, err
+	}
+	return parseStaticDoc(source), nil
+}
+
+// Return the first element matching the CSS selector css. The error matches
+// ErrNoSuchElement if nothing matches.
+//
+// Supported selectors are type, universal, #id, .class and attribute ([a],
+// [a=v], [a~=v], [a|=v], [a^=v], [a$=v], [a*=v]) selectors, the descendant,
+// child (>), next-sibling (+) and subsequent-sibling (~) combinators and
+// selector lists (a, b). Pseudo-classes are not supported.
+func (d *StaticDoc) Find(css string) (*StaticElement, error) {
+	return d.root.Find(css)
+}
+
+// Return all the elements matching the CSS selector css, in document order.
+func (d *StaticDoc) FindAll(css string) ([]*StaticElement, error) {
+	return d.root.FindAll(css)
+}
+
+// Return the first descendant of e matching the CSS selector css, see
+// StaticDoc.Find.
+func (e *StaticElement) Find(css string) (*StaticElement, error) {
+	elems, err := e.FindAll(css)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchElement, css)
+	}
+	return elems[0], nil
+}
+
+// Return the descendants of e matching the CSS selector css, in document
+// order.
+func (e *StaticElement) FindAll(css string) ([]*StaticElement, error) {
+	selectors, err := parseCSSSelector(css)
+	if err != nil {
+		return nil, err
+	}
+	var found []*StaticElement
+	e.walk(func(n *StaticElement) {
+		for _, sel := range selectors {
+			if sel.match(n, e) {
+				found = append(found, n)
+				return
+			}
+		}
+	})
+	return found, nil
+}
+
+// Get the value of the attribute name, the bool result is false if the
+// element doesn't have it.
+func (e *StaticElement) Attribute(name string) (string, bool) {
+	v, ok := e.Attrs[strings.ToLower(name)]
+	return v, ok
+}
+
+// The text of the element and its descendants (as textContent) with collapsed
+// whitespace, the content of script and style elements excluded. Unlike
+// WebElement.Text the CSS of the page is not applied: the text of hidden
+// elements is included and blocks are not separated (<br> is a space).
+func (e *StaticElement) Text() string {
+	var b strings.Builder
+	var collect func(n *StaticElement)
+	collect = func(n *StaticElement) {
+		if n.Tag == "" {
+			b.WriteString(n.text)
+			return
+		}
+		if n.Tag == "script" || n.Tag == "style" {
+			return
+		}
+		if n.Tag == "br" {
+			b.WriteByte(' ')
+			return
+		}
+		for _, c := range n.children {
+			collect(c)
+		}
+	}
+	collect(e)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// call f for every element descendant of e, in document order.
+func (e *StaticElement) walk(f func(*StaticElement)) {
+	for _, c := range e.children {
+		if c.Tag != "" {
+			f(c)
+			c.walk(f)
+		}
+	}
+}
+
+// the element children of e.
+func (e *StaticElement) elements() []*StaticElement {
+	var elems []*StaticElement
+	for _, c := range e.children {
+		if c.Tag != "" {
+			elems = append(elems, c)
+		}
+	}
+	return elems
+}
+
+// the element siblings of e before it, the closest first.
+func (e *StaticElement) previousSiblings() []*StaticElement {
+	if e.parent == nil {
+		return nil
+	}
+	var prev []*StaticElement
+	for _, c := range e.parent.elements() {
+		if c == e {
+			break
+		}
+		prev = append([]*StaticElement{c}, prev...)
+	}
+	return prev
+}
+
+// HTML parsing.
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// elements whose content is not parsed as HTML.
+var rawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// elements implicitly closed when one of the given elements starts.
+var impliedEnd = map[string][]string{
+	"li":     {"li"},
+	"dt":     {"dt", "dd"},
+	"dd":     {"dt", "dd"},
+	"option": {"option"},
+	"tr":     {"tr", "td", "th"},
+	"td":     {"td", "th"},
+	"th":     {"td", "th"},
+	"p":      {"p"},
+	"div":    {"p"},
+	"ul":     {"p"},
+	"ol":     {"p"},
+	"table":  {"p"},
+	"h1":     {"p"},
+	"h2":     {"p"},
+	"h3":     {"p"},
+	"h4":     {"p"},
+	"h5":     {"p"},
+	"h6":     {"p"},
+}
+
+func parseStaticDoc(src string) *StaticDoc {
+	root := &StaticElement{Tag: "#document"}
+	current := root
+	appendChild := func(n *StaticElement) {
+		n.parent = current
+		current.children = append(current.children, n)
+	}
+	for i := 0; i < len(src); {
+		if src[i] != '<' {
+			j := strings.IndexByte(src[i:], '<')
+			if j < 0 {
+				j = len(src) - i
+			}
+			appendChild(&StaticElement{text: html.UnescapeString(src[i : i+j])})
+			i += j
+			continue
+		}
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			i += skipPast(rest, "-->", 4)
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			i += skipPast(rest, ">", 2)
+		case strings.HasPrefix(rest, "</"):
+			name, n := readTagName(rest[2:])
+			i += skipPast(rest, ">", 2+n)
+			for e := current; e != root; e = e.parent {
+				if e.Tag == name {
+					current = e.parent
+					break
+				}
+			}
+		case len(rest) > 1 && isLetter(rest[1]):
+			name, n := readTagName(rest[1:])
+			attrs, selfClosing, m := readAttributes(rest[1+n:])
+			i += 1 + n + m
+			for current != root && containsString(impliedEnd[name], current.Tag) {
+				current = current.parent
+			}
+			elem := &StaticElement{Tag: name, Attrs: attrs}
+			appendChild(elem)
+			if voidElements[name] || selfClosing {
+				continue
+			}
+			if rawTextElements[name] {
+				end := indexEndTag(src[i:], name)
+				text := src[i : i+end]
+				if name == "textarea" || name == "title" {
+					text = html.UnescapeString(text)
+				}
+				elem.children = []*StaticElement{{text: text, parent: elem}}
+				i += end
+				continue
+			}
+			current = elem
+		default:
+			// a lone "<"
+			appendChild(&StaticElement{text: "<"})
+			i++
+		}
+	}
+	return &StaticDoc{root: root}
+}
+
+// the index in s of the end tag of the element name (compared case
+// insensitively), len(s) if missing. Lowering s to search it would change the
+// byte length of some runes and give a wrong index.
+func indexEndTag(s, name string) int {
+	tag := "</" + name
+	for j := 0; j+len(tag) <= len(s); j++ {
+		if s[j] == '<' && strings.EqualFold(s[j:j+len(tag)], tag) {
+			return j
+		}
+	}
+	return len(s)
+}
+
+// the length of s up to the end of the first sep after from, all of s if
+// missing.
+func skipPast(s, sep string, from int) int {
+	if from > len(s) {
+		return len(s)
+	}
+	j := strings.Index(s[from:], sep)
+	if j < 0 {
+		return len(s)
+	}
+	return from + j + len(sep)
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// read a tag name at the start of s, lowercased, and its length.
+func readTagName(s string) (string, int) {
+	n := 0
+	for n < len(s) && !isSpace(s[n]) && s[n] != '/' && s[n] != '>' {
+		n++
+	}
+	return strings.ToLower(s[:n]), n
+}
+
+// read the attributes of a start tag up to its closing ">", returning them,
+// if the tag is self-closing ("/>") and the length read.
+func readAttributes(s string) (map[string]string, bool, int) {
+	attrs := map[string]string{}
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case isSpace(c):
+			i++
+			continue
+		case c == '>':
+			return attrs, false, i + 1
+		case c == '/' && i+1 < len(s) && s[i+1] == '>':
+			return attrs, true, i + 2
+		case c == '/':
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && !(s[i] == '/' && i+1 < len(s) && s[i+1] == '>') {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) || s[i] != '=' {
+			if _, ok := attrs[name]; !ok {
+				attrs[name] = ""
+			}
+			continue
+		}
+		i++
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		var value string
+		if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			value = s[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			start := i
+			for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+				i++
+			}
+			value = s[start:i]
+		}
+		// the first occurrence of an attribute wins, as in browsers
+		if _, ok := attrs[name]; !ok {
+			attrs[name] = html.UnescapeString(value)
+		}
+	}
+	return attrs, false, len(s)
+}
+
+// CSS selectors.
+
+// a complex selector: compound selectors joined by combinators, the subject
+// of the selector is the last compound.
+type cssComplex struct {
+	compounds []cssCompound
+	// combinators[i] joins compounds[i] and compounds[i+1]: ' ', '>', '+', '~'
+	combinators []byte
+}
+
+type cssCompound struct {
+	tag     string // empty or "*" for any element
+	id      string
+	classes []string
+	attrs   []cssAttr
+}
+
+type cssAttr struct {
+	name, op, value string
+}
+
+// parse a selector list.
+func parseCSSSelector(css string) ([]cssComplex, error) {
+	var list []cssComplex
+	for _, part := range splitSelectorList(css) {
+		sel, err := parseCSSComplex(strings.TrimSpace(part))
+		if err != nil {
+			return nil, errors.New("invalid selector " + css + ": " + err.Error())
+		}
+		list = append(list, sel)
+	}
+	return list, nil
+}
+
+// split a selector list at the commas outside of strings.
+func splitSelectorList(css string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(css); i++ {
+		switch c := css[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			parts = append(parts, css[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, css[start:])
+}
+
+func parseCSSComplex(s string) (cssComplex, error) {
+	var sel cssComplex
+	if s == "" {
+		return sel, errors.New("empty selector")
+	}
+	i := 0
+	for {
+		compound, n, err := parseCSSCompound(s[i:])
+		if err != nil {
+			return sel, err
+		}
+		sel.compounds = append(sel.compounds, compound)
+		i += n
+		combinator := byte(0)
+		for i < len(s) && isSpace(s[i]) {
+			combinator = ' '
+			i++
+		}
+		if i == len(s) {
+			return sel, nil
+		}
+		if c := s[i]; c == '>' || c == '+' || c == '~' {
+			combinator = c
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+		}
+		if combinator == 0 {
+			return sel, errors.New("unexpected " + string(s[i]))
+		}
+		sel.combinators = append(sel.combinators, combinator)
+	}
+}
+
+func isIdentChar(c byte) bool {
+	return isLetter(c) || '0' <= c && c <= '9' || c == '-' || c == '_' || c >= 0x80
+}
+
+func readIdent(s string) (string, int) {
+	n := 0
+	for n < len(s) && isIdentChar(s[n]) {
+		n++
+	}
+	return s[:n], n
+}
+
+func parseCSSCompound(s string) (cssCompound, int, error) {
+	var c cssCompound
+	i := 0
+	if i < len(s) && s[i] == '*' {
+		c.tag = "*"
+		i++
+	} else {
+		tag, n := readIdent(s)
+		c.tag = strings.ToLower(tag)
+		i += n
+	}
+	for i < len(s) {
+		switch s[i] {
+		case '#', '.':
+			name, n := readIdent(s[i+1:])
+			if n == 0 {
+				return c, 0, errors.New("missing name after " + string(s[i]))
+			}
+			if s[i] == '#' {
+				c.id = name
+			} else {
+				c.classes = append(c.classes, name)
+			}
+			i += 1 + n
+		case '[':
+			attr, n, err := parseCSSAttr(s[i+1:])
+			if err != nil {
+				return c, 0, err
+			}
+			c.attrs = append(c.attrs, attr)
+			i += 1 + n
+		case ':':
+			return c, 0, errors.New("pseudo-classes are not supported")
+		default:
+			if i == 0 {
+				return c, 0, errors.New("unexpected " + string(s[i]))
+			}
+			return c, i, nil
+		}
+	}
+	if i == 0 {
+		return c, 0, errors.New("missing selector")
+	}
+	return c, i, nil
+}
+
+// parse an attribute selector after its "[", returning the length read
+// including the closing "]".
+func parseCSSAttr(s string) (cssAttr, int, error) {
+	var a cssAttr
+	i := 0
+	skipSpaces := func() {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+	}
+	skipSpaces()
+	name, n := readIdent(s[i:])
+	if n == 0 {
+		return a, 0, errors.New("missing attribute name")
+	}
+	a.name = strings.ToLower(name)
+	i += n
+	skipSpaces()
+	if i < len(s) && s[i] == ']' {
+		return a, i + 1, nil
+	}
+	if i < len(s) && s[i] == '=' {
+		a.op = "="
+		i++
+	} else if i+1 < len(s) && strings.IndexByte("~|^$*", s[i]) >= 0 && s[i+1] == '=' {
+		a.op = s[i : i+2]
+		i += 2
+	} else {
+		return a, 0, errors.New("invalid attribute selector")
+	}
+	skipSpaces()
+	if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+		end := strings.IndexByte(s[i+1:], s[i])
+		if end < 0 {
+			return a, 0, errors.New("unterminated string")
+		}
+		a.value = s[i+1 : i+1+end]
+		i += end + 2
+	} else {
+		value, n := readIdent(s[i:])
+		a.value = value
+		i += n
+	}
+	skipSpaces()
+	if i >= len(s) || s[i] != ']' {
+		return a, 0, errors.New("missing ]")
+	}
+	return a, i + 1, nil
+}
+
+// reports if e matches sel, with the ancestors considered up to scope
+// (excluded).
+func (sel cssComplex) match(e, scope *StaticElement) bool {
+	return sel.matchAt(len(sel.compounds)-1, e, scope)
+}
+
+func (sel cssComplex) matchAt(i int, e, scope *StaticElement) bool {
+	if !sel.compounds[i].match(e) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch sel.combinators[i-1] {
+	case ' ':
+		for p := e.parent; p != nil && p != scope; p = p.parent {
+			if sel.matchAt(i-1, p, scope) {
+				return true
+			}
+		}
+	case '>':
+		if p := e.parent; p != nil && p != scope {
+			return sel.matchAt(i-1, p, scope)
+		}
+	case '+':
+		if prev := e.previousSiblings(); len(prev) > 0 {
+			return sel.matchAt(i-1, prev[0], scope)
+		}
+	case '~':
+		for _, prev := range e.previousSiblings() {
+			if sel.matchAt(i-1, prev, scope) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c cssCompound) match(e *StaticElement) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != e.Tag {
+		return false
+	}
+	if c.id != "" && e.Attrs["id"] != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(e.Attrs["class"])
+		for _, class := range c.classes {
+			if !containsString(classes, class) {
+				return false
+			}
+		}
+	}
+	for _, a := range c.attrs {
+		value, ok := e.Attrs[a.name]
+		if !ok || !a.match(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a cssAttr) match(value string) bool {
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return value == a.value
+	case "~=":
+		return containsString(strings.Fields(value), a.value)
+	case "|=":
+		return value == a.value || strings.HasPrefix(value, a.value+"-")
+	case "^=":
+		return a.value != "" && strings.HasPrefix(value, a.value)
+	case "$=":
+		return a.value != "" && strings.HasSuffix(value, a.value)
+	case "*=":
+		return a.value != "" && strings.Contains(value, a.value)
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"testing"
+)
+
+const staticTestPage = `<!DOCTYPE html>
+<html><head><title>Orders &amp; more</title>
+<script>if (a < b && c > d) { document.write("<div class=x>"); }</script>
+</head><body>
+<!-- <div class="x">commented</div> -->
+<ul id=menu><li class="item active"><a href="/home">Home</a><li class=item><a href='/orders?a=1&amp;b=2'>Orders</a></ul>
+<table><tr data-id="order-1"><td>1<td>Pen <b>blue</b><tr data-id="order-2"><td>2<td>Ink</table>
+<p>first<p>second<br>line
+<input type=checkbox checked disabled/>
+</body></html>`
+
+func TestStaticDoc(t *testing.T) {
+	doc := parseStaticDoc(staticTestPage)
+	texts := func(css string) []string {
+		elems, err := doc.FindAll(css)
+		if err != nil {
+			t.Fatalf("%s: %s", css, err)
+		}
+		var texts []string
+		for _, e := range elems {
+			texts = append(texts, e.Text())
+		}
+		return texts
+	}
+	for css, want := range map[string][]string{
+		"title":                          {"Orders & more"},
+		"div.x":                          nil,
+		"#menu > li.item":                {"Home", "Orders"},
+		"li.active + li a":               {"Orders"},
+		"ul li ~ li":                     {"Orders"},
+		"tr[data-id^=order] td":          {"1", "Pen blue", "2", "Ink"},
+		`tr[data-id$="2"] > td`:          {"2", "Ink"},
+		"body > p":                       {"first", "second line"},
+		"a[href*='b=2'], b":              {"Orders", "blue"},
+		"[data-id='order-1'] *":          {"1", "Pen blue", "blue"},
+		"input[checked][type~=checkbox]": {""},
+	} {
+		got := texts(css)
+		if len(got) != len(want) {
+			t.Errorf("%s: got %q, want %q", css, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: got %q, want %q", css, got, want)
+				break
+			}
+		}
+	}
+	link, err := doc.Find("li:not(.active) a")
+	if err == nil {
+		t.Errorf("pseudo-class accepted: %+v", link)
+	}
+	link, err = doc.Find("#menu .item:last-child")
+	if err == nil {
+		t.Errorf("pseudo-class accepted: %+v", link)
+	}
+	link, err = doc.Find("li + li a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if href, ok := link.Attribute("HREF"); !ok || href != "/orders?a=1&b=2" {
+		t.Errorf("wrong href: %q, %v", href, ok)
+	}
+	if _, err := doc.Find("form"); !errors.Is(err, ErrNoSuchElement) {
+		t.Errorf("expected ErrNoSuchElement, got %v", err)
+	}
+	row, err := doc.Find("tr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cells, _ := row.FindAll("td"); len(cells) != 2 {
+		t.Errorf("row has %d cells", len(cells))
+	}
+	if cells, _ := row.FindAll("tr td"); len(cells) != 0 {
+		t.Errorf("scoped search matched ancestors: %d cells", len(cells))
+	}
+}
+
+func TestStaticDocRawTextNonASCII(t *testing.T) {
+	// lowering İ changes its byte length
+	doc := parseStaticDoc(`<title>İİİ</title><script>var s = "İstanbul";</SCRIPT><p>ok</p>`)
+	title, err := doc.Find("title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := title.Text(); text != "İİİ" {
+		t.Errorf("wrong title: %q", text)
+	}
+	script, err := doc.Find("script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := script.children[0].text; text != `var s = "İstanbul";` {
+		t.Errorf("wrong script: %q", text)
+	}
+	if p, err := doc.Find("p"); err != nil || p.Text() != "ok" {
+		t.Errorf("element after the raw text: %v", err)
+	}
+}
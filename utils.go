@@ -7,13 +7,32 @@ package webdriver
 import (
 	"errors"
 	"fmt"
+	"log"
 	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
 	"time"
 )
 
 var debug = false
 
+// Logger receives the warnings of the package, e.g. a *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Where the warnings of the package are written, nil discards them.
+// Default: standard error
+var WarningLogger Logger = log.New(os.Stderr, "webdriver: ", log.LstdFlags)
+
+func warnf(format string, v ...interface{}) {
+	if WarningLogger != nil {
+		WarningLogger.Printf(format, v...)
+	}
+}
+
 func debugprint(message interface{}) {
 	if debug {
 		pc, _, line, ok := runtime.Caller(1)
@@ -26,21 +45,65 @@ func debugprint(message interface{}) {
 	}
 }
 
-//probe d.Port until get a reply or timeout is up
-func probePort(port int, timeout time.Duration) error {
+// Interval between the probes of probePort, starting at probeInterval and
+// growing up to probeMaxInterval: a driver that starts quickly is seen
+// quickly, a slow one is not probed too often.
+var (
+	probeInterval    = 50 * time.Millisecond
+	probeMaxInterval = 250 * time.Millisecond
+)
+
+//probe port until it accepts a connection or timeout is up. Connection errors
+//(e.g. connection refused while the driver is starting) are retried. Probing
+//stops immediately when stop is closed (can be nil), e.g. when the process
+//exits. On timeout the error reports the last connection error.
+func probePort(port int, timeout time.Duration, stop <-chan struct{}) error {
 	address := fmt.Sprintf("127.0.0.1:%d", port)
-	now := time.Now()
+	deadline := time.Now().Add(timeout)
+	interval := probeInterval
 	for {
-		if conn, err := net.Dial("tcp", address); err == nil {
-			if err = conn.Close(); err != nil {
-				return err
-			}
-			break
+		select {
+		case <-stop:
+			return errors.New("start failed: process exited")
+		default:
+		}
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			return conn.Close()
 		}
-		if time.Since(now) > timeout {
-			return errors.New("start failed: timeout expired")
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("start failed: timeout expired after %s: %s", timeout, err)
 		}
-		time.Sleep(1 * time.Second)
+		if interval > remaining {
+			interval = remaining
+		}
+		select {
+		case <-stop:
+			return errors.New("start failed: process exited")
+		case <-time.After(interval):
+		}
+		if interval = interval * 3 / 2; interval > probeMaxInterval {
+			interval = probeMaxInterval
+		}
+	}
+}
+
+//check that the binary at path exists and is executable, bare names are
+//looked up in PATH. Returns the resolved path.
+func verifyBinary(path, name string) (string, error) {
+	if path == "" {
+		return "", errors.New(name + " path not set")
+	}
+	resolved, err := exec.LookPath(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if filepath.Base(path) == path {
+		return "", fmt.Errorf("%s not found in PATH", path)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", fmt.Errorf("%s not found: %s", name, path)
 	}
-	return nil
+	return "", fmt.Errorf("%s is not executable: %s", name, path)
 }
@@ -0,0 +1,105 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyBinary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webdriver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := filepath.Join(dir, "fakedriver")
+	if err := ioutil.WriteFile(exe, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	noexe := filepath.Join(dir, "noexe")
+	if err := ioutil.WriteFile(noexe, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := verifyBinary(exe, "fakedriver"); err != nil || path != exe {
+		t.Errorf("executable: got %q, %v", path, err)
+	}
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"", "chromedriver path not set"},
+		{"no-such-chromedriver", "no-such-chromedriver not found in PATH"},
+		{filepath.Join(dir, "missing"), "chromedriver not found: " + filepath.Join(dir, "missing")},
+		{noexe, "chromedriver is not executable: " + noexe},
+	}
+	for _, test := range tests {
+		_, err := verifyBinary(test.path, "chromedriver")
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v, want %q", test.path, err, test.err)
+		}
+	}
+}
+
+func TestCheckPortFree(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	d := NewChromeDriver("chromedriver")
+	d.Port = port
+	d.RequirePort = true
+	if err := d.Start(); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Fatalf("expected a port in use error, got %v", err)
+	}
+	if err := checkPortFree("127.0.0.1", 0); err == nil {
+		t.Fatal("expected an error for port 0")
+	}
+}
+
+func TestProbePort(t *testing.T) {
+	port, err := GetFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = probePort(port, 100*time.Millisecond, nil)
+	if err == nil || !strings.Contains(err.Error(), "timeout expired") || !strings.Contains(err.Error(), "refused") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	stop := make(chan struct{})
+	close(stop)
+	start := time.Now()
+	if err := probePort(port, 10*time.Second, stop); err == nil || !strings.Contains(err.Error(), "process exited") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("stop not seen immediately")
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		time.Sleep(2 * time.Second)
+		l.Close()
+	}()
+	start = time.Now()
+	if err := probePort(port, 5*time.Second, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("port seen after %s", elapsed)
+	}
+}
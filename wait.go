@@ -0,0 +1,327 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ErrWaitTimeout is returned (possibly wrapped with a description of the
+// condition that was not met) when a wait helper gives up.
+var ErrWaitTimeout = errors.New("wait timeout expired")
+
+// Interval between two checks of a wait condition.
+var pollInterval = 200 * time.Millisecond
+
+// call cond until it returns true or an error, or timeout is up.
+// cond is always checked at least once.
+func poll(timeout time.Duration, cond func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := cond()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrWaitTimeout
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Check cond immediately and then until it returns true or timeout is up.
+// cond returning an error counts as not met, the last error is reported on
+// timeout. The returned error wraps ErrWaitTimeout and includes desc, e.g.:
+//
+//	condition "cart badge shows 3" not met within 5s (last error: ...)
+func (s Session) Eventually(desc string, cond func() (bool, error), timeout time.Duration) error {
+	var last error
+	err := poll(timeout, func() (bool, error) {
+		ok, err := cond()
+		if err != nil {
+			last = err
+			return false, nil
+		}
+		return ok, nil
+	})
+	if err == nil {
+		return nil
+	}
+	if last != nil {
+		return fmt.Errorf("%w: condition %q not met within %s (last error: %s)", ErrWaitTimeout, desc, timeout, last)
+	}
+	return fmt.Errorf("%w: condition %q not met within %s", ErrWaitTimeout, desc, timeout)
+}
+
+// returns the status code of a CommandError, -1 if err is not a CommandError.
+func statusCode(err error) int {
+	switch e := err.(type) {
+	case *CommandError:
+		return e.StatusCode
+	case CommandError:
+		return e.StatusCode
+	}
+	return -1
+}
+
+// reports if err means the element is (no longer) on the page.
+func isElementGone(err error) bool {
+	c := statusCode(err)
+	return c == NoSuchElement || c == StaleElementReference
+}
+
+// Wait until an element is present, displayed and enabled and return it.
+// On timeout the returned error wraps ErrWaitTimeout and describes the last
+// condition that was not met.
+func (s Session) WaitForClickable(using FindElementStrategy, value string, timeout time.Duration) (WebElement, error) {
+	var elem WebElement
+	var last string
+	err := poll(timeout, func() (bool, error) {
+		we, err := s.FindElement(using, value)
+		if isElementGone(err) {
+			last = "not present"
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		displayed, err := we.IsDisplayed()
+		if isElementGone(err) {
+			last = "not present"
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if !displayed {
+			last = "not displayed"
+			return false, nil
+		}
+		enabled, err := we.IsEnabled()
+		if isElementGone(err) {
+			last = "not present"
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if !enabled {
+			last = "disabled"
+			return false, nil
+		}
+		elem = we
+		return true, nil
+	})
+	if err == ErrWaitTimeout {
+		return WebElement{}, fmt.Errorf("%w: element %s %q %s", ErrWaitTimeout, using, value, last)
+	}
+	return elem, err
+}
+
+// Read the current value of the attribute of el and wait until it changes.
+// If el is removed from the page while waiting this is treated as a change and
+// newVal is "". On timeout the returned error wraps ErrWaitTimeout.
+func (s Session) WaitForAttributeChange(el WebElement, attr string, timeout time.Duration) (oldVal, newVal string, err error) {
+	oldVal, err = el.GetAttribute(attr)
+	if err != nil {
+		return "", "", err
+	}
+	err = poll(timeout, func() (bool, error) {
+		v, err := el.GetAttribute(attr)
+		if isElementGone(err) {
+			newVal = ""
+			return true, nil
+		} else if err != nil {
+			return false, err
+		}
+		newVal = v
+		return v != oldVal, nil
+	})
+	if err == ErrWaitTimeout {
+		return oldVal, newVal, fmt.Errorf("%w: attribute %q still %q", ErrWaitTimeout, attr, oldVal)
+	}
+	return oldVal, newVal, err
+}
+
+// Wait until at least minCount elements match and return them. On timeout the
+// elements found by the last search are returned with an error wrapping
+// ErrWaitTimeout that reports the count (e.g. "expected 5, got 3").
+func (s Session) FindElementsWait(using FindElementStrategy, value string, minCount int, timeout time.Duration) ([]WebElement, error) {
+	var elems []WebElement
+	err := poll(timeout, func() (bool, error) {
+		we, err := s.FindElements(using, value)
+		if err != nil {
+			return false, err
+		}
+		elems = we
+		return len(elems) >= minCount, nil
+	})
+	if err == ErrWaitTimeout {
+		return elems, fmt.Errorf("%w: elements %s %q: expected %d, got %d", ErrWaitTimeout, using, value, minCount, len(elems))
+	}
+	return elems, err
+}
+
+// Wait until a descendant of the element matches and return it. Unlike a wait
+// on the session, a match elsewhere in the page doesn't satisfy it. If the
+// element itself is removed from the page (it goes stale) while waiting, an
+// error is returned immediately: the search would never succeed. On timeout
+// the returned error wraps ErrWaitTimeout.
+func (e WebElement) WaitForChild(using FindElementStrategy, value string, timeout time.Duration) (WebElement, error) {
+	var child WebElement
+	err := poll(timeout, func() (bool, error) {
+		we, err := e.FindElement(using, value)
+		switch statusCode(err) {
+		case StaleElementReference:
+			return false, fmt.Errorf("wait for child failed: container no longer in the page: %w", err)
+		case NoSuchElement:
+			return false, nil
+		}
+		child = we
+		return err == nil, err
+	})
+	if err == ErrWaitTimeout {
+		return WebElement{}, fmt.Errorf("%w: child %s %q not present", ErrWaitTimeout, using, value)
+	}
+	return child, err
+}
+
+// Wait until the position and size of el didn't change for stableFor, e.g.
+// before clicking an element sliding in with a CSS transition.
+// On timeout the returned error wraps ErrWaitTimeout.
+func (s Session) WaitForStable(el WebElement, stableFor, timeout time.Duration) error {
+	var last Rect
+	var since time.Time
+	err := poll(timeout, func() (bool, error) {
+		rect, err := el.Rect()
+		if err != nil {
+			return false, err
+		}
+		if since.IsZero() || rect != last {
+			last = rect
+			since = time.Now()
+			return false, nil
+		}
+		return time.Since(since) >= stableFor, nil
+	})
+	if err == ErrWaitTimeout {
+		return fmt.Errorf("%w: element still moving, last rect %+v", ErrWaitTimeout, last)
+	}
+	return err
+}
+
+// Wait until the element is removed from the page, i.e. commands on it fail
+// with a stale element reference or no such element error, e.g. to confirm
+// that a deleted row is gone. On timeout the returned error wraps
+// ErrWaitTimeout.
+func (e WebElement) WaitUntilStale(timeout time.Duration) error {
+	err := poll(timeout, func() (bool, error) {
+		_, err := e.Name()
+		if isElementGone(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err == ErrWaitTimeout {
+		return fmt.Errorf("%w: element %s still on the page", ErrWaitTimeout, e.id)
+	}
+	return err
+}
+
+// Wait until the value of the attribute of el matches re and return it, e.g.
+// the style of a progress bar reaching "width: 100%". If el is removed from
+// the page the stale element error is returned at once. On timeout the
+// returned error wraps ErrWaitTimeout and reports the last value.
+func (s Session) WaitForAttributeMatch(el WebElement, attr string, re *regexp.Regexp, timeout time.Duration) (string, error) {
+	var last string
+	err := poll(timeout, func() (bool, error) {
+		v, err := el.GetAttribute(attr)
+		if err != nil {
+			return false, err
+		}
+		last = v
+		return re.MatchString(v), nil
+	})
+	if err == ErrWaitTimeout {
+		return last, fmt.Errorf("%w: attribute %q is %q, doesn't match %s", ErrWaitTimeout, attr, last, re)
+	}
+	return last, err
+}
+
+// Wait until the page title is not empty and unchanged between two checks and
+// return it, to avoid asserting on the transient title seen while navigating
+// (see Title). On timeout the returned error wraps ErrWaitTimeout.
+func (s Session) TitleStable(timeout time.Duration) (string, error) {
+	var last string
+	var seen bool
+	err := poll(timeout, func() (bool, error) {
+		title, err := s.Title()
+		if err != nil {
+			return false, err
+		}
+		stable := seen && title == last && title != ""
+		last, seen = title, true
+		return stable, nil
+	})
+	if err == ErrWaitTimeout {
+		return last, fmt.Errorf("%w: title not stable, last %q", ErrWaitTimeout, last)
+	}
+	return last, err
+}
+
+// A condition checked by Session.Wait.
+type WaitCondition func(s Session) (bool, error)
+
+// Check cond until it is true or timeout is up. Errors meaning that an element
+// is missing or stale count as the condition not met yet, other errors stop
+// the wait and are returned. On timeout the returned error wraps ErrWaitTimeout.
+func (s Session) Wait(cond WaitCondition, timeout time.Duration) error {
+	err := poll(timeout, func() (bool, error) {
+		return checkCondition(s, cond)
+	})
+	if err == ErrWaitTimeout {
+		return fmt.Errorf("%w: condition not met within %s", ErrWaitTimeout, timeout)
+	}
+	return err
+}
+
+// check cond, ignoring missing and stale element errors.
+func checkCondition(s Session, cond WaitCondition) (bool, error) {
+	ok, err := cond(s)
+	if isElementGone(err) {
+		return false, nil
+	}
+	return ok, err
+}
+
+// A condition true when all conds are true, e.g. an element displayed and a
+// spinner gone.
+func WaitAll(conds ...WaitCondition) WaitCondition {
+	return func(s Session) (bool, error) {
+		for _, cond := range conds {
+			ok, err := checkCondition(s, cond)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// A condition true when at least one of conds is true.
+func WaitAny(conds ...WaitCondition) WaitCondition {
+	return func(s Session) (bool, error) {
+		for _, cond := range conds {
+			ok, err := checkCondition(s, cond)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindElementsWaitPartial(t *testing.T) {
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/elements" {
+			return []map[string]string{{"ELEMENT": "1"}, {"ELEMENT": "2"}, {"ELEMENT": "3"}}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elems, err := session.FindElementsWait(CSS_Selector, "li", 5, 50*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("expected ErrWaitTimeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "expected 5, got 3") {
+		t.Errorf("count missing in error: %s", err)
+	}
+	if len(elems) != 3 || elems[2].id != "3" {
+		t.Errorf("partial result not returned: %+v", elems)
+	}
+	elems, err = session.FindElementsWait(CSS_Selector, "li", 3, 50*time.Millisecond)
+	if err != nil || len(elems) != 3 {
+		t.Errorf("got %d elements, %v", len(elems), err)
+	}
+}
+
+func TestWaitForStable(t *testing.T) {
+	var moves int
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/element/1/location":
+			if moves > 0 {
+				moves--
+			}
+			return map[string]interface{}{"x": 10, "y": 100 - 10*moves}
+		case "/session/fake-session/element/1/size":
+			return map[string]interface{}{"width": 50, "height": 20}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	el := WebElement{session, "1"}
+	moves = 3
+	if err := session.WaitForStable(el, 30*time.Millisecond, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if moves != 0 {
+		t.Errorf("returned while moving")
+	}
+	moves = 1000
+	if err := session.WaitForStable(el, 30*time.Millisecond, 100*time.Millisecond); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("expected ErrWaitTimeout, got %v", err)
+	}
+}
+
+func TestFindElementsWaitForAny(t *testing.T) {
+	var empty int
+	// delay of the empty results, as a driver applying the implicit wait
+	var delay time.Duration
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/elements" {
+			if empty > 0 {
+				empty--
+				time.Sleep(delay)
+				return []interface{}{}
+			}
+			return []map[string]string{{"ELEMENT": "1"}}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	// a copy made before the implicit wait is set still knows it
+	copied := *session
+	if err := session.SetTimeoutsImplicitWait(1000); err != nil {
+		t.Fatal(err)
+	}
+	empty = 2
+	if elems, err := copied.FindElements(CSS_Selector, "li"); err != nil || len(elems) != 0 {
+		t.Fatalf("default should not poll: %d elements, %v", len(elems), err)
+	}
+	copied.FindElementsWaitForAny = true
+	if elems, err := copied.FindElements(CSS_Selector, "li"); err != nil || len(elems) != 1 {
+		t.Fatalf("got %d elements, %v", len(elems), err)
+	}
+	empty = 1000
+	delay = 200 * time.Millisecond
+	if err := session.SetTimeoutsImplicitWait(200); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if elems, err := copied.FindElements(CSS_Selector, "li"); err != nil || len(elems) != 0 {
+		t.Fatalf("got %d elements, %v", len(elems), err)
+	}
+	if elapsed := time.Since(start); elapsed > 350*time.Millisecond {
+		t.Errorf("waited %s after the driver applied the implicit wait", elapsed)
+	}
+	delay = 0
+	if err := session.NoImplicitWait(); err != nil {
+		t.Fatal(err)
+	}
+	if elems, err := copied.FindElements(CSS_Selector, "li"); err != nil || len(elems) != 0 {
+		t.Fatalf("got %d elements, %v", len(elems), err)
+	}
+}
+
+func TestWaitUntilStale(t *testing.T) {
+	var probes int
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/element/row/name" {
+			probes++
+			if probes > 2 {
+				return &CommandError{StatusCode: StaleElementReference, Message: "stale element reference"}
+			}
+			return "tr"
+		}
+		if r.Path == "/session/fake-session/element/other/name" {
+			return "div"
+		}
+		if r.Path == "/session/fake-session/element/removed/name" {
+			return &w3cError{404, "stale element reference", "element is not attached to the page document"}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	if err := (WebElement{session, "row"}).WaitUntilStale(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := (WebElement{session, "removed"}).WaitUntilStale(time.Second); err != nil {
+		t.Errorf("W3C stale element error: %v", err)
+	}
+	if err := (WebElement{session, "other"}).WaitUntilStale(50 * time.Millisecond); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("expected ErrWaitTimeout, got %v", err)
+	}
+}
+
+func TestWaitForAttributeMatch(t *testing.T) {
+	var width int
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/element/bar/attribute/style":
+			if width < 100 {
+				width += 50
+			}
+			return fmt.Sprintf("width: %d%%", width)
+		case "/session/fake-session/element/gone/attribute/style":
+			return &CommandError{StatusCode: StaleElementReference, Message: "stale element reference"}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	done := regexp.MustCompile(`width: 100%`)
+	v, err := session.WaitForAttributeMatch(WebElement{session, "bar"}, "style", done, time.Second)
+	if err != nil || v != "width: 100%" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+	_, err = session.WaitForAttributeMatch(WebElement{session, "bar"}, "style", regexp.MustCompile(`width: 200%`), 50*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) || !strings.Contains(err.Error(), "width: 100%") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = session.WaitForAttributeMatch(WebElement{session, "gone"}, "style", done, time.Second)
+	if statusCode(err) != StaleElementReference {
+		t.Errorf("expected a stale element error, got %v", err)
+	}
+}
+
+func TestTitleStable(t *testing.T) {
+	titles := []string{"Old page", "", "New page"}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path == "/session/fake-session/title" {
+			title := titles[0]
+			if len(titles) > 1 {
+				titles = titles[1:]
+			}
+			return title
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	if title, err := session.TitleStable(time.Second); err != nil || title != "New page" {
+		t.Errorf("got %q, %v", title, err)
+	}
+}
+
+func TestEventually(t *testing.T) {
+	var s Session
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	calls := 0
+	err := s.Eventually("third call", func() (bool, error) {
+		calls++
+		if calls == 1 {
+			return false, errors.New("not ready")
+		}
+		return calls == 3, nil
+	}, time.Second)
+	if err != nil || calls != 3 {
+		t.Fatalf("%d calls, %v", calls, err)
+	}
+	err = s.Eventually("cart badge shows 3", func() (bool, error) {
+		return false, errors.New("badge shows 2")
+	}, 30*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) || !strings.Contains(err.Error(), `condition "cart badge shows 3" not met within 30ms (last error: badge shows 2)`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWaitAllAny(t *testing.T) {
+	var s Session
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	after := func(n int) WaitCondition {
+		calls := 0
+		return func(Session) (bool, error) {
+			calls++
+			return calls >= n, nil
+		}
+	}
+	never := func(Session) (bool, error) { return false, nil }
+	missing := func(Session) (bool, error) {
+		return false, &CommandError{StatusCode: NoSuchElement}
+	}
+	broken := func(Session) (bool, error) { return false, errors.New("broken") }
+
+	if err := s.Wait(WaitAll(after(2), after(3)), time.Second); err != nil {
+		t.Errorf("WaitAll: %v", err)
+	}
+	if err := s.Wait(WaitAll(after(1), never), 30*time.Millisecond); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("WaitAll with a false condition: %v", err)
+	}
+	if err := s.Wait(WaitAny(never, missing, after(3)), time.Second); err != nil {
+		t.Errorf("WaitAny: %v", err)
+	}
+	if err := s.Wait(WaitAny(never, missing), 30*time.Millisecond); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("WaitAny with false conditions: %v", err)
+	}
+	if err := s.Wait(WaitAny(never, broken), time.Second); err == nil || err.Error() != "broken" {
+		t.Errorf("error not propagated: %v", err)
+	}
+	if err := s.Wait(WaitAll(after(1), WaitAny(broken)), time.Second); err == nil || err.Error() != "broken" {
+		t.Errorf("nested error not propagated: %v", err)
+	}
+}
+
+func TestWaitForChild(t *testing.T) {
+	var searches int
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/element/panel/element":
+			searches++
+			if searches < 3 {
+				return &CommandError{StatusCode: NoSuchElement, Message: "no such element"}
+			}
+			return map[string]string{"ELEMENT": "row"}
+		case "/session/fake-session/element/gone/element":
+			return &CommandError{StatusCode: StaleElementReference, Message: "stale element reference"}
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	child, err := WebElement{session, "panel"}.WaitForChild(CSS_Selector, "tr", time.Second)
+	if err != nil || child.id != "row" {
+		t.Fatalf("got %+v, %v", child, err)
+	}
+	start := time.Now()
+	_, err = WebElement{session, "gone"}.WaitForChild(CSS_Selector, "tr", time.Second)
+	if err == nil || errors.Is(err, ErrWaitTimeout) || !strings.Contains(err.Error(), "container") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("stale container not detected until timeout")
+	}
+}
+
+func TestWaitForClickable(t *testing.T) {
+	var state string
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/element":
+			if state == "missing" {
+				return &CommandError{StatusCode: NoSuchElement, Message: "no such element"}
+			}
+			return map[string]string{"ELEMENT": "button"}
+		case "/session/fake-session/element/button/displayed":
+			return state != "hidden"
+		case "/session/fake-session/element/button/enabled":
+			return state != "disabled"
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	for failing, want := range map[string]string{
+		"missing":  `element css selector "#send" not present`,
+		"hidden":   `element css selector "#send" not displayed`,
+		"disabled": `element css selector "#send" disabled`,
+	} {
+		state = failing
+		_, err := session.WaitForClickable(CSS_Selector, "#send", 30*time.Millisecond)
+		if !errors.Is(err, ErrWaitTimeout) || !strings.HasSuffix(err.Error(), want) {
+			t.Errorf("%s: unexpected error: %v", failing, err)
+		}
+	}
+	state = "ready"
+	el, err := session.WaitForClickable(CSS_Selector, "#send", time.Second)
+	if err != nil || el.id != "button" {
+		t.Errorf("got %+v, %v", el, err)
+	}
+}
+
+func TestWaitForAttributeChange(t *testing.T) {
+	var reads int
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		switch r.Path {
+		case "/session/fake-session/element/status/attribute/class":
+			reads++
+			if reads > 2 {
+				return "done"
+			}
+			return "loading"
+		case "/session/fake-session/element/spinner/attribute/class":
+			reads++
+			if reads > 1 {
+				return &CommandError{StatusCode: StaleElementReference, Message: "stale element reference"}
+			}
+			return "spinning"
+		case "/session/fake-session/element/idle/attribute/class":
+			return "idle"
+		}
+		return nil
+	})
+	session, err := f.core().NewSession(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+	oldVal, newVal, err := session.WaitForAttributeChange(WebElement{session, "status"}, "class", time.Second)
+	if err != nil || oldVal != "loading" || newVal != "done" {
+		t.Errorf("got %q, %q, %v", oldVal, newVal, err)
+	}
+	reads = 0
+	oldVal, newVal, err = session.WaitForAttributeChange(WebElement{session, "spinner"}, "class", time.Second)
+	if err != nil || oldVal != "spinning" || newVal != "" {
+		t.Errorf("removed element: got %q, %q, %v", oldVal, newVal, err)
+	}
+	_, _, err = session.WaitForAttributeChange(WebElement{session, "idle"}, "class", 30*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) || !strings.Contains(err.Error(), `"idle"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
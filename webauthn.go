@@ -0,0 +1,98 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+)
+
+// Options of a virtual authenticator, see Session.AddVirtualAuthenticator.
+type AuthenticatorOptions struct {
+	// "ctap2" (passkeys) or "u2f". Default: "ctap2"
+	Protocol string
+	// "usb", "nfc", "ble", "cable" or "internal" (a platform authenticator,
+	// e.g. Touch ID). Default: "internal"
+	Transport string
+	// If it can store discoverable credentials (resident keys), as passkeys
+	// require.
+	HasResidentKey bool
+	// If it supports user verification; IsUserVerified is then the result of
+	// each verification.
+	HasUserVerification bool
+	IsUserVerified      bool
+	// If true, user presence (a touch) is not simulated automatically and
+	// operations time out waiting for it. Default: false
+	NoPresenceSimulation bool
+}
+
+// A credential stored by a virtual authenticator. Binary values are base64
+// encoded, as in CDP.
+type Credential struct {
+	CredentialId         string `json:"credentialId"`
+	IsResidentCredential bool   `json:"isResidentCredential"`
+	// the relying party, e.g. "example.com"
+	RpId       string `json:"rpId"`
+	PrivateKey string `json:"privateKey"`
+	UserHandle string `json:"userHandle"`
+	SignCount  int    `json:"signCount"`
+}
+
+// Add a virtual WebAuthn authenticator to the browser with CDP
+// WebAuthn.addVirtualAuthenticator and return its id. Registrations and logins
+// of the page (navigator.credentials) then use it, so that passkey or security
+// key flows can be tested without hardware or prompts. WebAuthn only works in
+// secure contexts: pages must be served over https or from localhost.
+// Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) AddVirtualAuthenticator(opts AuthenticatorOptions) (string, error) {
+	if opts.Protocol == "" {
+		opts.Protocol = "ctap2"
+	}
+	if opts.Transport == "" {
+		opts.Transport = "internal"
+	}
+	if err := s.ExecuteCDP("WebAuthn.enable", nil, nil); err != nil {
+		return "", err
+	}
+	args := map[string]interface{}{
+		"options": map[string]interface{}{
+			"protocol":                    opts.Protocol,
+			"transport":                   opts.Transport,
+			"hasResidentKey":              opts.HasResidentKey,
+			"hasUserVerification":         opts.HasUserVerification,
+			"isUserVerified":              opts.IsUserVerified,
+			"automaticPresenceSimulation": !opts.NoPresenceSimulation,
+		},
+	}
+	var result struct {
+		AuthenticatorId string `json:"authenticatorId"`
+	}
+	if err := s.ExecuteCDP("WebAuthn.addVirtualAuthenticator", args, &result); err != nil {
+		return "", err
+	}
+	if result.AuthenticatorId == "" {
+		return "", errors.New("add virtual authenticator failed: no authenticator id returned")
+	}
+	return result.AuthenticatorId, nil
+}
+
+// Remove the virtual authenticator id added with AddVirtualAuthenticator, and
+// its credentials.
+// Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) RemoveVirtualAuthenticator(id string) error {
+	return s.ExecuteCDP("WebAuthn.removeVirtualAuthenticator", map[string]interface{}{"authenticatorId": id}, nil)
+}
+
+// Get the credentials stored by the virtual authenticator id, e.g. to assert
+// that a registration created one for the expected relying party.
+// Chrome only, ErrUnsupportedCommand is returned for other browsers.
+func (s Session) GetCredentials(id string) ([]Credential, error) {
+	var result struct {
+		Credentials []Credential `json:"credentials"`
+	}
+	if err := s.ExecuteCDP("WebAuthn.getCredentials", map[string]interface{}{"authenticatorId": id}, &result); err != nil {
+		return nil, err
+	}
+	return result.Credentials, nil
+}
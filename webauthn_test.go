@@ -0,0 +1,60 @@
+// Copyright 2013 Federico Sogaro. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webdriver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVirtualAuthenticator(t *testing.T) {
+	var commands []string
+	var options map[string]interface{}
+	f := newFakeServer(t, func(r fakeRequest) interface{} {
+		if r.Path != "/session/fake-session/goog/cdp/execute" {
+			return nil
+		}
+		cmd := r.Body["cmd"].(string)
+		commands = append(commands, cmd)
+		args := r.Body["params"].(map[string]interface{})
+		switch cmd {
+		case "WebAuthn.addVirtualAuthenticator":
+			options = args["options"].(map[string]interface{})
+			return map[string]interface{}{"authenticatorId": "auth-1"}
+		case "WebAuthn.getCredentials":
+			if args["authenticatorId"] != "auth-1" {
+				return &CommandError{StatusCode: UnknownError, Message: "unknown authenticator"}
+			}
+			return map[string]interface{}{"credentials": []map[string]interface{}{
+				{"credentialId": "Y3JlZA==", "isResidentCredential": true, "rpId": "example.com", "signCount": 1},
+			}}
+		}
+		return map[string]interface{}{}
+	})
+	session := newFakeSession(t, f, "chrome")
+	id, err := session.AddVirtualAuthenticator(AuthenticatorOptions{HasResidentKey: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "auth-1" || commands[0] != "WebAuthn.enable" {
+		t.Errorf("got %q after %q", id, commands)
+	}
+	if options["protocol"] != "ctap2" || options["transport"] != "internal" || options["hasResidentKey"] != true || options["automaticPresenceSimulation"] != true {
+		t.Errorf("unexpected options: %v", options)
+	}
+	credentials, err := session.GetCredentials(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credentials) != 1 || credentials[0].RpId != "example.com" || !credentials[0].IsResidentCredential {
+		t.Errorf("unexpected credentials: %+v", credentials)
+	}
+	if err := session.RemoveVirtualAuthenticator(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newFakeSession(t, f, "firefox").AddVirtualAuthenticator(AuthenticatorOptions{}); !errors.Is(err, ErrUnsupportedCommand) {
+		t.Errorf("expected ErrUnsupportedCommand, got %v", err)
+	}
+}
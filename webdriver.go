@@ -6,11 +6,15 @@ package webdriver
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
-	//	"fmt"
+	"strings"
+	"sync"
+	"time"
 	//	"net/http"
 )
 
@@ -25,8 +29,11 @@ type WebDriver interface {
 	NewSession(desired, required Capabilities) (*Session, error)
 	//Returns a list of the currently active sessions.
 	Sessions() ([]Session, error)
+	//Create a session, run fn with it and delete it.
+	WithSession(caps Capabilities, fn func(*Session) error) error
 
-	do(params interface{}, method, urlFormat string, urlParams ...interface{}) (string, []byte, error)
+	do(label string, params interface{}, method, urlFormat string, urlParams ...interface{}) (string, []byte, error)
+	doRaw(ctx context.Context, label string, params interface{}, method, path string) (int, jsonResponse, error)
 }
 
 //typing saver
@@ -59,7 +66,187 @@ type Capabilities map[string]interface{}
 type Session struct {
 	Id           string
 	Capabilities Capabilities
-	wd           WebDriver
+	// If true FindElements polls up to the implicit wait for at least one
+	// match. The spec says that the implicit wait applies to FindElements too
+	// but some drivers return an empty list immediately. Only the implicit
+	// wait set through this Session (or a copy) is known. Default: false
+	FindElementsWaitForAny bool
+	// If true WebElement.SendKeysFiles uploads the files to the driver first,
+	// needed when the driver runs on another machine (e.g. Selenium Grid) and
+	// can't read the local paths. Default: false
+	UploadFiles bool
+
+	wd         WebDriver
+	label      string
+	// shared by the copies of the session, nil if not created by NewSession
+	state *sessionState
+	// if the driver speaks the W3C WebDriver protocol
+	w3c bool
+}
+
+// state of a session that must be seen by all its copies (methods have value
+// receivers and elements keep a copy of their session).
+type sessionState struct {
+	mu sync.Mutex
+	// last non-zero implicit wait (ms) set
+	implicitWait int
+	// implicit wait (ms) currently in effect
+	activeImplicitWait int
+	// type ("tab" or "window") of the windows opened with NewWindow, by handle
+	windowTypes map[string]string
+
+	// held while starting and stopping the CDP captures below, that are nil
+	// when not running
+	cdpMu      sync.Mutex
+	screencast *screencast
+	network    *networkCapture
+	intercept  *cdpConn
+	downloads  *downloadCapture
+}
+
+// the state shared by the copies of s; sessions not created by NewSession or
+// Sessions have none.
+func (s Session) sharedState() (*sessionState, error) {
+	if s.state == nil {
+		return nil, errors.New("session not created by NewSession or Sessions")
+	}
+	return s.state, nil
+}
+
+// record that the implicit wait is now ms. Nothing is recorded if st is nil.
+func (st *sessionState) setImplicitWait(ms int) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.activeImplicitWait = ms
+	if ms != 0 {
+		st.implicitWait = ms
+	}
+}
+
+// the last non-zero implicit wait set and the one in effect, in ms. Zero if st
+// is nil.
+func (st *sessionState) implicitWaits() (last, active int) {
+	if st == nil {
+		return 0, 0
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.implicitWait, st.activeImplicitWait
+}
+
+// Set a name to tell apart the session in debug output and in the errors of
+// its commands (e.g. "[Alice] ..."), useful when driving multiple browsers at
+// once.
+func (s *Session) SetLabel(name string) {
+	s.label = name
+}
+
+// The label of the session. Default: the first 8 characters of the session Id.
+func (s Session) Label() string {
+	if s.label != "" {
+		return s.label
+	}
+	if len(s.Id) > 8 {
+		return s.Id[:8]
+	}
+	return s.Id
+}
+
+// The WebDriver BiDi WebSocket endpoint returned by the driver when the session
+// was created with Capabilities.EnableBiDi. The bool result is false if the
+// driver didn't negotiate one.
+func (s Session) BiDiURL() (string, bool) {
+	url, ok := s.Capabilities.GetString("webSocketUrl")
+	return url, ok && url != ""
+}
+
+// send a command of the session to the webdriver.
+func (s Session) do(params interface{}, method, urlFormat string, urlParams ...interface{}) (string, []byte, error) {
+	label := s.Label()
+	sessionId, data, err := s.wd.do(label, params, method, urlFormat, urlParams...)
+	if cerr, ok := err.(*CommandError); ok {
+		cerr.Label = label
+	}
+	if SlowMo > 0 && method == "POST" && interactionCommands[strings.TrimPrefix(urlFormat, "/session/%s")] {
+		time.Sleep(SlowMo)
+	}
+	return sessionId, data, err
+}
+
+// The response of a command sent with Session.Do.
+type Response struct {
+	// HTTP status code
+	HTTPStatus int
+	// JSON Wire Protocol status (see the constants, e.g. NoSuchElement), 0 for
+	// W3C drivers
+	Status int
+	// the "value" of the response, as sent by the driver
+	Value json.RawMessage
+}
+
+// Send a command to an endpoint not covered by this package, e.g. of Appium or
+// of a vendor, with full control: any HTTP method, a body for any method
+// (e.g. DELETE) and cancellation through ctx.
+//
+// path is relative to the url of the session, e.g. "/appium/device/lock" is
+// sent to /session/{session id}/appium/device/lock. body, if not nil, is
+// encoded as JSON; POST requests always have a body, {} if nil. The value of
+// the response is decoded into result unless result is nil or the value is
+// null. Error responses return a *CommandError, as the other commands, with
+// the Response filled as far as it was received.
+//
+// Element references in the value are not converted, this is up to the
+// caller: a WebElement decoded from it is not bound to the session, decode the
+// reference id and use Session.WebElementFromId instead.
+func (s Session) Do(ctx context.Context, method, path string, body, result interface{}) (Response, error) {
+	label := s.Label()
+	code, jr, err := s.wd.doRaw(ctx, label, body, method, "/session/"+s.Id+path)
+	response := Response{HTTPStatus: code, Status: jr.Status, Value: jr.RawValue}
+	if cerr, ok := err.(*CommandError); ok {
+		cerr.Label = label
+	}
+	if err != nil || result == nil || isNull(jr.RawValue) {
+		return response, err
+	}
+	return response, json.Unmarshal(jr.RawValue, result)
+}
+
+// If not zero, each interaction command (navigation, click, key and mouse
+// input...) is followed by a pause of SlowMo, so that a human can follow a run
+// when debugging or in a demo. Commands that only read state are not affected.
+// Default: 0
+var SlowMo time.Duration
+
+// commands paused by SlowMo, as url formats without the "/session/%s" prefix.
+var interactionCommands = map[string]bool{
+	"/url":               true,
+	"/back":              true,
+	"/forward":           true,
+	"/refresh":           true,
+	"/element/%s/click":  true,
+	"/element/%s/clear":  true,
+	"/element/%s/submit": true,
+	"/element/%s/value":  true,
+	"/keys":              true,
+	"/moveto":            true,
+	"/click":             true,
+	"/doubleclick":       true,
+	"/buttondown":        true,
+	"/buttonup":          true,
+	"/actions":           true,
+	"/touch/click":       true,
+	"/touch/doubleclick": true,
+	"/touch/down":        true,
+	"/touch/up":          true,
+	"/touch/move":        true,
+	"/touch/scroll":      true,
+	"/touch/flick":       true,
+	"/touch/longclick":   true,
+	"/accept_alert":      true,
+	"/dismiss_alert":     true,
 }
 
 type WindowHandle struct {
@@ -98,8 +285,20 @@ const (
 	XPath = FindElementStrategy("xpath")
 )
 
+// key of element references in the W3C WebDriver protocol.
+const w3cElementKey = "element-6066-11e4-a52e-4f735466cecf"
+
 type element struct {
 	ELEMENT string
+	// W3C element reference, returned by drivers speaking the W3C protocol.
+	W3C string `json:"element-6066-11e4-a52e-4f735466cecf"`
+}
+
+func (e element) id() string {
+	if e.ELEMENT != "" {
+		return e.ELEMENT
+	}
+	return e.W3C
 }
 
 type WebElement struct {
@@ -107,13 +306,35 @@ type WebElement struct {
 	id string
 }
 
+// Encode the element as a web element reference, so it can be passed to
+// scripts and commands, e.g. as the origin of a pointer action.
+func (e WebElement) MarshalJSON() ([]byte, error) {
+	if e.s != nil && e.s.w3c {
+		return json.Marshal(map[string]string{w3cElementKey: e.id})
+	}
+	return json.Marshal(map[string]string{"ELEMENT": e.id})
+}
+
+// Decode a web element reference. The element is not bound to a session,
+// Session.Execute binds the elements it decodes.
+func (e *WebElement) UnmarshalJSON(data []byte) error {
+	var elem element
+	if err := json.Unmarshal(data, &elem); err != nil {
+		return err
+	}
+	e.id = elem.id()
+	return nil
+}
+
 type Cookie struct {
-	Name   string `json:"name"`
-	Value  string `json:"value"`
-	Path   string `json:"path"`
-	Domain string `json:"domain"`
-	Secure bool   `json:"secure"`
-	Expiry float64    `json:"expiry"`
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Path     string  `json:"path"`
+	Domain   string  `json:"domain"`
+	Secure   bool    `json:"secure"`
+	Expiry   float64 `json:"expiry,omitempty"`
+	HttpOnly bool    `json:"httpOnly,omitempty"`
+	SameSite string  `json:"sameSite,omitempty"`
 }
 
 type GeoLocation struct {
@@ -223,21 +444,24 @@ func (s Session) GetCapabilities() Capabilities {
 
 //Delete the session.
 func (s Session) Delete() error {
-	_, _, err := s.wd.do(nil, "DELETE", "/session/%s", s.Id)
+	_, _, err := s.do(nil, "DELETE", "/session/%s", s.Id)
 	return err
 }
 
 //Configure the amount of time that a particular type of operation can execute for before they are aborted and a |Timeout| error is returned to the client.  Valid values are: "script" for script timeouts, "implicit" for modifying the implicit wait timeout and "page load" for setting a page load timeout.
 func (s Session) SetTimeouts(typ string, ms int) error {
 	p := params{"type": typ, "ms": ms}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/timeouts", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/timeouts", s.Id)
+	if err == nil && typ == "implicit" {
+		s.state.setImplicitWait(ms)
+	}
 	return err
 }
 
 //Set the amount of time, in milliseconds, that asynchronous scripts executed by ExecuteScriptAsync() are permitted to run before they are aborted and a |Timeout| error is returned to the client.
 func (s Session) SetTimeoutsAsyncScript(ms int) error {
 	p := params{"ms": ms}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/timeouts/async_script", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/timeouts/async_script", s.Id)
 	return err
 }
 
@@ -245,7 +469,35 @@ func (s Session) SetTimeoutsAsyncScript(ms int) error {
 //If this command is never sent, the driver should default to an implicit wait of 0ms.
 func (s Session) SetTimeoutsImplicitWait(ms int) error {
 	p := params{"ms": ms}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/timeouts/implicit_wait", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/timeouts/implicit_wait", s.Id)
+	if err == nil {
+		s.state.setImplicitWait(ms)
+	}
+	return err
+}
+
+// Set the implicit wait to zero, so that searching for missing elements fails
+// fast. The previous value is kept and can be restored with RestoreImplicitWait.
+// The value is shared by all the copies of the session, including the sessions
+// of its elements.
+func (s Session) NoImplicitWait() error {
+	p := params{"ms": 0}
+	_, _, err := s.do(p, "POST", "/session/%s/timeouts/implicit_wait", s.Id)
+	if err == nil {
+		s.state.setImplicitWait(0)
+	}
+	return err
+}
+
+// Restore the last non-zero implicit wait set with SetTimeoutsImplicitWait (or
+// SetTimeouts), zero if it has never been set.
+func (s Session) RestoreImplicitWait() error {
+	last, _ := s.state.implicitWaits()
+	p := params{"ms": last}
+	_, _, err := s.do(p, "POST", "/session/%s/timeouts/implicit_wait", s.Id)
+	if err == nil {
+		s.state.setImplicitWait(last)
+	}
 	return err
 }
 
@@ -255,7 +507,7 @@ func (s Session) GetCurrentWindowHandle() WindowHandle {
 
 //Retrieve the current window handle.
 func (s Session) WindowHandle() (WindowHandle, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/window_handle", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/window_handle", s.Id)
 	if err != nil {
 		return WindowHandle{}, err
 	}
@@ -266,7 +518,7 @@ func (s Session) WindowHandle() (WindowHandle, error) {
 
 //Retrieve the list of all window handles available to the session.
 func (s Session) WindowHandles() ([]WindowHandle, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/window_handles", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/window_handles", s.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -284,7 +536,7 @@ func (s Session) WindowHandles() ([]WindowHandle, error) {
 
 //Retrieve the URL of the current page.
 func (s Session) GetUrl() (string, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/url", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/url", s.Id)
 	if err != nil {
 		return "", err
 	}
@@ -296,25 +548,25 @@ func (s Session) GetUrl() (string, error) {
 //Navigate to a new URL.
 func (s Session) Url(url string) error {
 	p := params{"url": url}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/url", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/url", s.Id)
 	return err
 }
 
 //Navigate forwards in the browser history, if possible.
 func (s Session) Forward() error {
-	_, _, err := s.wd.do(nil, "POST", "/session/%s/forward", s.Id)
+	_, _, err := s.do(nil, "POST", "/session/%s/forward", s.Id)
 	return err
 }
 
 //Navigate backwards in the browser history, if possible.
 func (s Session) Back() error {
-	_, _, err := s.wd.do(nil, "POST", "/session/%s/back", s.Id)
+	_, _, err := s.do(nil, "POST", "/session/%s/back", s.Id)
 	return err
 }
 
 //Refresh the current page.
 func (s Session) Refresh() error {
-	_, _, err := s.wd.do(nil, "POST", "/session/%s/refresh", s.Id)
+	_, _, err := s.do(nil, "POST", "/session/%s/refresh", s.Id)
 	return err
 }
 
@@ -323,7 +575,7 @@ func (s Session) Refresh() error {
 // Arguments may be any JSON-primitive, array, or JSON object. JSON objects that define a WebElement reference will be converted to the corresponding DOM element. Likewise, any WebElements in the script result will be returned to the client as WebElement JSON objects.
 func (s Session) ExecuteScript(script string, args []interface{}) ([]byte, error) {
 	p := params{"script": script, "args": args}
-	_, data, err := s.wd.do(p, "POST", "/session/%s/execute", s.Id)
+	_, data, err := s.do(p, "POST", "/session/%s/execute", s.Id)
 	return data, err
 }
 
@@ -333,13 +585,13 @@ func (s Session) ExecuteScript(script string, args []interface{}) ([]byte, error
 // Arguments may be any JSON-primitive, array, or JSON object. JSON objects that define a WebElement reference will be converted to the corresponding DOM element. Likewise, any WebElements in the script result will be returned to the client as WebElement JSON objects.
 func (s Session) ExecuteScriptAsync(script string, args []interface{}) ([]byte, error) {
 	p := params{"script": script, "args": args}
-	_, data, err := s.wd.do(p, "POST", "/session/%s/execute_async", s.Id)
+	_, data, err := s.do(p, "POST", "/session/%s/execute_async", s.Id)
 	return data, err
 }
 
 //Take a screenshot of the current page.
 func (s Session) Screenshot() ([]byte, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/screenshot", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/screenshot", s.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -348,9 +600,51 @@ func (s Session) Screenshot() ([]byte, error) {
 	return ioutil.ReadAll(decoder)
 }
 
+// messages of errors meaning that the browser or the session are gone, even
+// if reported as unknown errors.
+var sessionDeadMessages = []string{"session deleted", "invalid session id", "not connected to devtools", "chrome not reachable", "browser has closed", "no such window"}
+
+// reports if err is a transient screenshot failure, e.g. "unable to capture
+// screen" right after a navigation, that can succeed if retried.
+func isTransientScreenshotError(err error) bool {
+	switch statusCode(err) {
+	case UnknownError, Timeout, ScriptTimeout:
+	default:
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, dead := range sessionDeadMessages {
+		if strings.Contains(msg, dead) {
+			return false
+		}
+	}
+	return true
+}
+
+// Take a screenshot like Screenshot, retrying up to attempts times with
+// interval between attempts when the capture fails transiently (e.g. "unable
+// to capture screen" while the page is still loading). Errors meaning the
+// session or the window are gone, or the driver is not reachable, are
+// returned immediately. Useful in failure handlers, where a missing screenshot
+// means no debugging artifact at all.
+func (s Session) ScreenshotRetry(attempts int, interval time.Duration) ([]byte, error) {
+	var err error
+	for i := 0; i < attempts || i == 0; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		var data []byte
+		data, err = s.Screenshot()
+		if err == nil || !isTransientScreenshotError(err) {
+			return data, err
+		}
+	}
+	return nil, fmt.Errorf("screenshot failed after %d attempts: %w", attempts, err)
+}
+
 //List all available engines on the machine.
 func (s Session) IMEAvailableEngines() ([]string, error) {
-	_, data, err := s.wd.do(nil, "GET", "session/%s/ime/available_engines", s.Id)
+	_, data, err := s.do(nil, "GET", "session/%s/ime/available_engines", s.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -361,7 +655,7 @@ func (s Session) IMEAvailableEngines() ([]string, error) {
 
 //Get the name of the active IME engine.
 func (s Session) IMEActiveEngine() (string, error) {
-	_, data, err := s.wd.do(nil, "GET", "session/%s/ime/active_engine", s.Id)
+	_, data, err := s.do(nil, "GET", "session/%s/ime/active_engine", s.Id)
 	if err != nil {
 		return "", err
 	}
@@ -372,7 +666,7 @@ func (s Session) IMEActiveEngine() (string, error) {
 
 //Indicates whether IME input is active at the moment (not if it's available).
 func (s Session) IsIMEActivated() (bool, error) {
-	_, data, err := s.wd.do(nil, "GET", "session/%s/ime/activated", s.Id)
+	_, data, err := s.do(nil, "GET", "session/%s/ime/activated", s.Id)
 	if err != nil {
 		return false, err
 	}
@@ -383,14 +677,14 @@ func (s Session) IsIMEActivated() (bool, error) {
 
 //De-activates the currently-active IME engine.
 func (s Session) IMEDeactivate() error {
-	_, _, err := s.wd.do(nil, "GET", "session/%s/ime/deactivate", s.Id)
+	_, _, err := s.do(nil, "GET", "session/%s/ime/deactivate", s.Id)
 	return err
 }
 
 //Make an engines that is available (appears on the list returned by getAvailableEngines) active.
 func (s Session) IMEActivate(engine string) error {
 	p := params{"engine": engine}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/ime/activate", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/ime/activate", s.Id)
 	return err
 }
 
@@ -406,39 +700,124 @@ func (s Session) FocusOnFrame(frameId interface{}) error {
 		}
 	}
 	p := params{"id": frameId}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/frame", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/frame", s.Id)
 	return err
 }
 
 // Change focus back to parent frame
 func (s Session) FocusParentFrame() error {
-	_, _, err := s.wd.do(nil, "POST", "/session/%s/frame/parent", s.Id)
+	_, _, err := s.do(nil, "POST", "/session/%s/frame/parent", s.Id)
 	return err
 }
 
+// Find the iframe (or frame) matching the CSS selector css in the current
+// browsing context and change focus to it. The error matches ErrNoSuchElement
+// if nothing matches css. If the frame is replaced between the search and the
+// switch (e.g. re-rendered by the page) the search is repeated once.
+func (s Session) SwitchToFrameBySelector(css string) error {
+	for attempt := 0; ; attempt++ {
+		frame, err := s.FindElement(CSS_Selector, css)
+		if err != nil {
+			return err
+		}
+		name, err := frame.Name()
+		if err == nil {
+			if name = strings.ToLower(name); name != "iframe" && name != "frame" {
+				return fmt.Errorf("switch to frame failed: %q matches a <%s>, not a frame", css, name)
+			}
+			err = s.FocusOnFrame(frame)
+		}
+		if statusCode(err) != StaleElementReference || attempt > 0 {
+			return err
+		}
+	}
+}
+
 //Change focus to another window. The window to change focus to may be specified by its server assigned window handle, or by the value of its name attribute.
 func (s Session) FocusOnWindow(name string) error {
 	p := params{"name": name}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/window", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/window", s.Id)
 	return err
 }
 
+// Types of window for NewWindow.
+const (
+	WindowTypeTab    = "tab"
+	WindowTypeWindow = "window"
+)
+
+//Open a new top-level browsing context, typ is WindowTypeTab or WindowTypeWindow
+//and is a hint, the browser can open the other type. Focus doesn't change.
+func (s Session) NewWindow(typ string) (WindowHandle, error) {
+	p := params{"type": typ}
+	_, data, err := s.do(p, "POST", "/session/%s/window/new", s.Id)
+	if err != nil {
+		return WindowHandle{}, err
+	}
+	var window struct {
+		Handle string `json:"handle"`
+		Type   string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &window); err != nil {
+		return WindowHandle{}, err
+	}
+	if st := s.state; st != nil {
+		st.mu.Lock()
+		if st.windowTypes == nil {
+			st.windowTypes = map[string]string{}
+		}
+		st.windowTypes[window.Handle] = window.Type
+		st.mu.Unlock()
+	}
+	return WindowHandle{&s, window.Handle}, nil
+}
+
+//Get the type, "tab" or "window", of a window opened with NewWindow, "unknown"
+//for other windows. If handle is not an open window an error is returned.
+func (s Session) WindowType(handle string) (string, error) {
+	handles, err := s.WindowHandles()
+	if err != nil {
+		return "", err
+	}
+	for _, h := range handles {
+		if h.id != handle {
+			continue
+		}
+		var typ string
+		if st := s.state; st != nil {
+			st.mu.Lock()
+			typ = st.windowTypes[handle]
+			st.mu.Unlock()
+		}
+		if typ != "" {
+			return typ, nil
+		}
+		return "unknown", nil
+	}
+	return "", errors.New("window type failed: no such window: " + handle)
+}
+
+//The server assigned handle of the window.
+func (w WindowHandle) Id() string {
+	return w.id
+}
+
 //Close the current window.
 func (s Session) CloseCurrentWindow() error {
-	_, _, err := s.wd.do(nil, "DELETE", "/session/%s/window", s.Id)
+	_, _, err := s.do(nil, "DELETE", "/session/%s/window", s.Id)
 	return err
 }
 
 //Change the size of the specified window.
 func (w WindowHandle) SetSize(size Size) error {
 	p := params{"width": size.Width, "height": size.Height}
-	_, _, err := w.s.wd.do(p, "POST", "/session/%s/window/%s/size", w.s.Id, w.id)
+	_, _, err := w.s.do(p, "POST", "/session/%s/window/%s/size", w.s.Id, w.id)
 	return err
 }
 
 //Get the size of the specified window.
 func (w WindowHandle) GetSize() (Size, error) {
-	_, data, err := w.s.wd.do(nil, "GET", "/session/%s/window/%s/size", w.s.Id, w.id)
+	_, data, err := w.s.do(nil, "GET", "/session/%s/window/%s/size", w.s.Id, w.id)
 	if err != nil {
 		return Size{}, err
 	}
@@ -450,13 +829,13 @@ func (w WindowHandle) GetSize() (Size, error) {
 //Change the position of the specified window.
 func (w WindowHandle) SetPosition(position Position) error {
 	p := params{"x": position.X, "y": position.Y}
-	_, _, err := w.s.wd.do(p, "POST", "/session/%s/window/%s/position", w.s.Id, w.id)
+	_, _, err := w.s.do(p, "POST", "/session/%s/window/%s/position", w.s.Id, w.id)
 	return err
 }
 
 //Get the position of the specified window.
 func (w WindowHandle) GetPosition() (Position, error) {
-	_, data, err := w.s.wd.do(nil, "GET", "/session/%s/window/%s/position", w.s.Id, w.id)
+	_, data, err := w.s.do(nil, "GET", "/session/%s/window/%s/position", w.s.Id, w.id)
 	if err != nil {
 		return Position{}, err
 	}
@@ -466,14 +845,42 @@ func (w WindowHandle) GetPosition() (Position, error) {
 }
 
 //Maximize the specified window if not already maximized.
+//Headless browsers often have nothing to maximize to: if the driver doesn't
+//know the command or the size of the window doesn't change, the window is
+//moved to 0,0 and resized to window.screen.availWidth/availHeight, i.e.
+//whatever screen size the headless browser reports.
 func (w WindowHandle) MaximizeWindow() error {
-	_, _, err := w.s.wd.do(nil, "POST", "/session/%s/window/%s/maximize", w.s.Id, w.id)
-	return err
+	before, sizeErr := w.GetSize()
+	_, _, err := w.s.do(nil, "POST", "/session/%s/window/%s/maximize", w.s.Id, w.id)
+	if err != nil && !errors.Is(err, ErrUnsupportedCommand) {
+		return err
+	}
+	if err == nil {
+		if sizeErr != nil {
+			return nil
+		}
+		if after, err := w.GetSize(); err != nil || after != before {
+			return nil
+		}
+	}
+	return w.resizeToScreen()
+}
+
+func (w WindowHandle) resizeToScreen() error {
+	var screen Size
+	script := "return {width: window.screen.availWidth, height: window.screen.availHeight};"
+	if err := w.s.executeScript(script, nil, &screen); err != nil {
+		return err
+	}
+	if err := w.SetPosition(Position{0, 0}); err != nil {
+		return err
+	}
+	return w.SetSize(screen)
 }
 
 //Retrieve all cookies visible to the current page.
 func (s Session) GetCookies() ([]Cookie, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/cookie", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/cookie", s.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -484,7 +891,7 @@ func (s Session) GetCookies() ([]Cookie, error) {
 
 //Retrieve raw cookies data visible to the current page.
 func (s Session) GetRawCookies() ([]byte, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/cookie", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/cookie", s.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -493,26 +900,29 @@ func (s Session) GetRawCookies() ([]byte, error) {
 
 //Set a cookie.
 func (s Session) SetCookie(cookie Cookie) error {
+	if err := validSameSite(cookie.SameSite); err != nil {
+		return err
+	}
 	p := params{"cookie": cookie}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/cookie", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/cookie", s.Id)
 	return err
 }
 
 //Delete all cookies visible to the current page.
 func (s Session) DeleteCookies() error {
-	_, _, err := s.wd.do(nil, "DELETE", "/session/%s/cookie", s.Id)
+	_, _, err := s.do(nil, "DELETE", "/session/%s/cookie", s.Id)
 	return err
 }
 
 //Delete the cookie with the given name.
 func (s Session) DeleteCookieByName(name string) error {
-	_, _, err := s.wd.do(nil, "DELETE", "/session/%s/cookie/%s", s.Id, name)
+	_, _, err := s.do(nil, "DELETE", "/session/%s/cookie/%s", s.Id, name)
 	return err
 }
 
 //Get the current page source.
 func (s Session) Source() (string, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/source", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/source", s.Id)
 	if err != nil {
 		return "", err
 	}
@@ -522,8 +932,11 @@ func (s Session) Source() (string, error) {
 }
 
 //Get the current page title.
+//The title is read when the command runs, it is not cached: right after a
+//navigation it can still be the title of the previous page or briefly empty.
+//Use TitleStable to wait for the title to settle.
 func (s Session) Title() (string, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/title", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/title", s.Id)
 	if err != nil {
 		return "", err
 	}
@@ -539,19 +952,44 @@ func (s Session) WebElementFromId(id string) WebElement {
 //Search for an element on the page, starting from the document root.
 func (s Session) FindElement(using FindElementStrategy, value string) (WebElement, error) {
 	p := params{"using": using, "value": value}
-	_, data, err := s.wd.do(p, "POST", "/session/%s/element", s.Id)
+	_, data, err := s.do(p, "POST", "/session/%s/element", s.Id)
 	if err != nil {
 		return WebElement{}, err
 	}
 	var elem element
 	err = json.Unmarshal(data, &elem)
-	return WebElement{&s, elem.ELEMENT}, err
+	return WebElement{&s, elem.id()}, err
 }
 
 //Search for multiple elements on the page, starting from the document root.
+//If FindElementsWaitForAny is set and no element matches, the search is
+//repeated until the implicit wait expires. The time the driver already waited
+//for the first search counts, so that drivers applying the implicit wait are
+//not delayed further.
 func (s Session) FindElements(using FindElementStrategy, value string) ([]WebElement, error) {
+	start := time.Now()
+	elements, err := s.findElements(using, value)
+	_, active := s.state.implicitWaits()
+	if err != nil || len(elements) > 0 || !s.FindElementsWaitForAny || active <= 0 {
+		return elements, err
+	}
+	remaining := time.Duration(active)*time.Millisecond - time.Since(start)
+	if remaining <= 0 {
+		return elements, nil
+	}
+	err = poll(remaining, func() (bool, error) {
+		elements, err = s.findElements(using, value)
+		return len(elements) > 0, err
+	})
+	if err == ErrWaitTimeout {
+		err = nil
+	}
+	return elements, err
+}
+
+func (s Session) findElements(using FindElementStrategy, value string) ([]WebElement, error) {
 	p := params{"using": using, "value": value}
-	_, data, err := s.wd.do(p, "POST", "/session/%s/elements", s.Id)
+	_, data, err := s.do(p, "POST", "/session/%s/elements", s.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -562,20 +1000,20 @@ func (s Session) FindElements(using FindElementStrategy, value string) ([]WebEle
 	}
 	elements := make([]WebElement, len(v))
 	for i, elem := range v {
-		elements[i] = WebElement{&s, elem.ELEMENT}
+		elements[i] = WebElement{&s, elem.id()}
 	}
 	return elements, err
 }
 
 //Get the element on the page that currently has focus.
 func (s Session) GetActiveElement() (WebElement, error) {
-	_, data, err := s.wd.do(nil, "POST", "/session/%s/element/active", s.Id)
+	_, data, err := s.do(nil, "POST", "/session/%s/element/active", s.Id)
 	if err != nil {
 		return WebElement{}, err
 	}
 	var elem element
 	err = json.Unmarshal(data, &elem)
-	return WebElement{&s, elem.ELEMENT}, err
+	return WebElement{&s, elem.id()}, err
 }
 
 //Describe the identified element. This command is reserved for future use; its return type is currently undefined.
@@ -586,19 +1024,19 @@ func (s Session) GetActiveElement() (WebElement, error) {
 //Search for an element on the page, starting from the identified element.
 func (e WebElement) FindElement(using FindElementStrategy, value string) (WebElement, error) {
 	p := params{"using": using, "value": value}
-	_, data, err := e.s.wd.do(p, "POST", "/session/%s/element/%s/element", e.s.Id, e.id)
+	_, data, err := e.s.do(p, "POST", "/session/%s/element/%s/element", e.s.Id, e.id)
 	if err != nil {
 		return WebElement{}, err
 	}
 	var elem element
 	err = json.Unmarshal(data, &elem)
-	return WebElement{e.s, elem.ELEMENT}, err
+	return WebElement{e.s, elem.id()}, err
 }
 
 //Search for multiple elements on the page, starting from the identified element.
 func (e WebElement) FindElements(using FindElementStrategy, value string) ([]WebElement, error) {
 	p := params{"using": using, "value": value}
-	_, data, err := e.s.wd.do(p, "POST", "/session/%s/element/%s/elements", e.s.Id, e.id)
+	_, data, err := e.s.do(p, "POST", "/session/%s/element/%s/elements", e.s.Id, e.id)
 	if err != nil {
 		return nil, err
 	}
@@ -609,26 +1047,26 @@ func (e WebElement) FindElements(using FindElementStrategy, value string) ([]Web
 	}
 	elements := make([]WebElement, len(v))
 	for i, z := range v {
-		elements[i] = WebElement{e.s, z.ELEMENT}
+		elements[i] = WebElement{e.s, z.id()}
 	}
 	return elements, err
 }
 
 //Click on an element.
 func (e WebElement) Click() error {
-	_, _, err := e.s.wd.do(nil, "POST", "/session/%s/element/%s/click", e.s.Id, e.id)
+	_, _, err := e.s.do(nil, "POST", "/session/%s/element/%s/click", e.s.Id, e.id)
 	return err
 }
 
 //Submit a FORM element.
 func (e WebElement) Submit() error {
-	_, _, err := e.s.wd.do(nil, "POST", "/session/%s/element/%s/submit", e.s.Id, e.id)
+	_, _, err := e.s.do(nil, "POST", "/session/%s/element/%s/submit", e.s.Id, e.id)
 	return err
 }
 
 //Returns the visible text for the element.
 func (e WebElement) Text() (string, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/text", e.s.Id, e.id)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/text", e.s.Id, e.id)
 	if err != nil {
 		return "", err
 	}
@@ -639,29 +1077,52 @@ func (e WebElement) Text() (string, error) {
 
 //Send a sequence of key strokes to an element.
 func (e WebElement) SendKeys(sequence string) error {
-	keys := make([]string, len(sequence))
-	for i, k := range sequence {
-		keys[i] = string(k)
-	}
-	p := params{"value": keys}
-	_, _, err := e.s.wd.do(p, "POST", "/session/%s/element/%s/value", e.s.Id, e.id)
+	p := params{"value": splitKeys(sequence)}
+	_, _, err := e.s.do(p, "POST", "/session/%s/element/%s/value", e.s.Id, e.id)
 	return err
 }
 
+// the keys of sequence, one string per rune: special keys and non-ASCII
+// characters are multi-byte.
+func splitKeys(sequence string) []string {
+	keys := make([]string, 0, len(sequence))
+	for _, k := range sequence {
+		keys = append(keys, string(k))
+	}
+	return keys
+}
+
+// Send the characters of text to an element one at a time, waiting delay
+// between them, like a human typing. Useful with widgets that debounce input
+// and drop fast key strokes. Special keys (e.g. EnterKey) are sent as a single
+// key stroke.
+//
+// Every character is a separate command, so typing long strings is slow:
+// expect len(text) round trips plus (len(text)-1)*delay.
+func (e WebElement) TypeWithDelay(text string, delay time.Duration) error {
+	first := true
+	for _, k := range text {
+		if !first {
+			time.Sleep(delay)
+		}
+		first = false
+		if err := e.SendKeys(string(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //Send a sequence of key strokes to the active element.
 func (s Session) SendKeysOnActiveElement(sequence string) error {
-	keys := make([]string, len(sequence))
-	for i, k := range sequence {
-		keys[i] = string(k)
-	}
-	p := params{"value": keys}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/keys", s.Id)
+	p := params{"value": splitKeys(sequence)}
+	_, _, err := s.do(p, "POST", "/session/%s/keys", s.Id)
 	return err
 }
 
 //Query for an element's tag name.
 func (e WebElement) Name() (string, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/name", e.s.Id, e.id)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/name", e.s.Id, e.id)
 	if err != nil {
 		return "", err
 	}
@@ -672,13 +1133,13 @@ func (e WebElement) Name() (string, error) {
 
 //Clear a TEXTAREA or text INPUT element's value.
 func (e WebElement) Clear() error {
-	_, _, err := e.s.wd.do(nil, "POST", "/session/%s/element/%s/clear", e.s.Id, e.id)
+	_, _, err := e.s.do(nil, "POST", "/session/%s/element/%s/clear", e.s.Id, e.id)
 	return err
 }
 
 //Determine if an OPTION element, or an INPUT element of type checkbox or radiobutton is currently selected.
 func (e WebElement) IsSelected() (bool, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/value", e.s.Id, e.id)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/selected", e.s.Id, e.id)
 	if err != nil {
 		return false, err
 	}
@@ -689,7 +1150,7 @@ func (e WebElement) IsSelected() (bool, error) {
 
 //Determine if an element is currently enabled.
 func (e WebElement) IsEnabled() (bool, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/enabled", e.s.Id, e.id)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/enabled", e.s.Id, e.id)
 	if err != nil {
 		return false, err
 	}
@@ -700,7 +1161,7 @@ func (e WebElement) IsEnabled() (bool, error) {
 
 //Get the value of an element's attribute.
 func (e WebElement) GetAttribute(name string) (string, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/attribute/%s", e.s.Id, e.id, name)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/attribute/%s", e.s.Id, e.id, name)
 	if err != nil {
 		return "", err
 	}
@@ -712,7 +1173,7 @@ func (e WebElement) GetAttribute(name string) (string, error) {
 
 //Test if two element IDs refer to the same DOM element.
 func (e WebElement) Equal(element WebElement) (bool, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/equal/%s", e.s.Id, e.id, element.id)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/equal/%s", e.s.Id, e.id, element.id)
 	if err != nil {
 		return false, err
 	}
@@ -723,7 +1184,7 @@ func (e WebElement) Equal(element WebElement) (bool, error) {
 
 //Determine if an element is currently displayed.
 func (e WebElement) IsDisplayed() (bool, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/displayed", e.s.Id, e.id)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/displayed", e.s.Id, e.id)
 	if err != nil {
 		return false, err
 	}
@@ -735,7 +1196,7 @@ func (e WebElement) IsDisplayed() (bool, error) {
 //Determine an element's location on the page.
 //The point (0, 0) refers to the upper-left corner of the page. The element's coordinates are returned as a JSON object with x and y properties.
 func (e WebElement) GetLocation() (Position, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/location", e.s.Id, e.id)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/location", e.s.Id, e.id)
 	if err != nil {
 		return Position{}, err
 	}
@@ -748,7 +1209,7 @@ func (e WebElement) GetLocation() (Position, error) {
 //
 //Note: This is considered an internal command and should only be used to determine an element's location for correctly generating native events.
 func (e WebElement) GetLocationInView() (Position, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/location_in_view", e.s.Id, e.id)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/location_in_view", e.s.Id, e.id)
 	if err != nil {
 		return Position{}, err
 	}
@@ -759,7 +1220,7 @@ func (e WebElement) GetLocationInView() (Position, error) {
 
 //Determine an element's size in pixels.
 func (e WebElement) Size() (Size, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/size", e.s.Id, e.id)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/size", e.s.Id, e.id)
 	if err != nil {
 		return Size{}, err
 	}
@@ -768,9 +1229,40 @@ func (e WebElement) Size() (Size, error) {
 	return size, err
 }
 
+// Position and size of an element, in CSS pixels relative to the document.
+type Rect struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// Get the position and size of the element. Uses the W3C rect command in W3C
+// sessions, location and size otherwise.
+func (e WebElement) Rect() (Rect, error) {
+	if e.s.w3c {
+		_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/rect", e.s.Id, e.id)
+		if err != nil {
+			return Rect{}, err
+		}
+		var rect Rect
+		err = json.Unmarshal(data, &rect)
+		return rect, err
+	}
+	position, err := e.GetLocation()
+	if err != nil {
+		return Rect{}, err
+	}
+	size, err := e.Size()
+	if err != nil {
+		return Rect{}, err
+	}
+	return Rect{position.X, position.Y, float64(size.Width), float64(size.Height)}, nil
+}
+
 //Query the value of an element's computed CSS property.
 func (e WebElement) GetCssProperty(name string) (string, error) {
-	_, data, err := e.s.wd.do(nil, "GET", "/session/%s/element/%s/css/%s", e.s.Id, e.id, name)
+	_, data, err := e.s.do(nil, "GET", "/session/%s/element/%s/css/%s", e.s.Id, e.id, name)
 	if err != nil {
 		return "", err
 	}
@@ -789,7 +1281,7 @@ const (
 
 //Get the current browser orientation.
 func (s Session) GetOrientation() (ScreenOrientation, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/orientation", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/orientation", s.Id)
 	if err != nil {
 		return "", err
 	}
@@ -801,13 +1293,13 @@ func (s Session) GetOrientation() (ScreenOrientation, error) {
 //Set the browser orientation.
 func (s Session) SetOrientation(orientation ScreenOrientation) error {
 	p := params{"orientation": orientation}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/orientation", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/orientation", s.Id)
 	return err
 }
 
 //Gets the text of the currently displayed JavaScript alert(), confirm(), or prompt() dialog.
 func (s Session) GetAlertText() (string, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/alert_text", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/alert_text", s.Id)
 	if err != nil {
 		return "", err
 	}
@@ -819,19 +1311,19 @@ func (s Session) GetAlertText() (string, error) {
 //Sends keystrokes to a JavaScript prompt() dialog.
 func (s Session) SetAlertText(text string) error {
 	p := params{"text": text}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/alert_text", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/alert_text", s.Id)
 	return err
 }
 
 //Accepts the currently displayed alert dialog.
 func (s Session) AcceptAlert() error {
-	_, _, err := s.wd.do(nil, "POST", "/session/%s/accept_alert", s.Id)
+	_, _, err := s.do(nil, "POST", "/session/%s/accept_alert", s.Id)
 	return err
 }
 
 //Dismisses the currently displayed alert dialog.
 func (s Session) DismissAlert() error {
-	_, _, err := s.wd.do(nil, "POST", "/session/%s/dismiss_alert", s.Id)
+	_, _, err := s.do(nil, "POST", "/session/%s/dismiss_alert", s.Id)
 	return err
 }
 
@@ -839,14 +1331,14 @@ func (s Session) DismissAlert() error {
 //If no element is specified, the move is relative to the current mouse cursor. If an element is provided but no offset, the mouse will be moved to the center of the element. If the element is not visible, it will be scrolled into view.
 func (s Session) MoveTo(element WebElement, xoffset, yoffset int) error {
 	p := params{"element": element.id, "xoffset": xoffset, "yoffset": yoffset}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/moveto", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/moveto", s.Id)
 	return err
 }
 
 //Move the mouse to the center of the specificed element.
 func (s Session) MoveToCenter(element WebElement) error {
 	p := params{"element": element.id}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/moveto", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/moveto", s.Id)
 	return err
 }
 
@@ -863,76 +1355,76 @@ const (
 //Note that calling this command after calling buttondown and before calling button up (or any out-of-order interactions sequence) will yield undefined behaviour).
 func (s Session) Click(button MouseButton) error {
 	p := params{"button": button}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/click", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/click", s.Id)
 	return err
 }
 
 //Click and hold the left mouse button (at the coordinates set by the last moveto command).
 func (s Session) ButtonDown(button MouseButton) error {
 	p := params{"button": button}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/buttondown", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/buttondown", s.Id)
 	return err
 }
 
 //Releases the mouse button previously held (where the mouse is currently at).
 func (s Session) ButtonUp(button MouseButton) error {
 	p := params{"button": button}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/buttonup", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/buttonup", s.Id)
 	return err
 }
 
 //Double-clicks at the current mouse coordinates (set by moveto).
 func (s Session) DoubleClick() error {
-	_, _, err := s.wd.do(nil, "POST", "/session/%s/doubleclick", s.Id)
+	_, _, err := s.do(nil, "POST", "/session/%s/doubleclick", s.Id)
 	return err
 }
 
 //Single tap on the touch enabled device.
 func (s Session) TouchClick(element WebElement) error {
 	p := params{"element": element.id}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/touch/click", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/touch/click", s.Id)
 	return err
 }
 
 //Finger down on the screen.
 func (s Session) TouchDown(x, y int) error {
 	p := params{"x": x, "y": y}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/touch/down", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/touch/down", s.Id)
 	return err
 }
 
 //Finger up on the screen.
 func (s Session) TouchUp(x, y int) error {
 	p := params{"x": x, "y": y}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/touch/up", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/touch/up", s.Id)
 	return err
 }
 
 //Finger move on the screen.
 func (s Session) TouchMove(x, y int) error {
 	p := params{"x": x, "y": y}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/touch/move", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/touch/move", s.Id)
 	return err
 }
 
 //Scroll on the touch screen using finger based motion events.
 func (s Session) TouchScroll(element WebElement, xoffset, yoffset int) error {
 	p := params{"element": element.id, "xoffset": xoffset, "yoffset": yoffset}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/touch/scroll", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/touch/scroll", s.Id)
 	return err
 }
 
 //Double tap on the touch screen using finger motion events.
 func (s Session) TouchDoubleClick(element WebElement) error {
 	p := params{"element": element.id}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/touch/doubleclick", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/touch/doubleclick", s.Id)
 	return err
 }
 
 //Long press on the touch screen using finger motion events.
 func (s Session) TouchLongClick(element WebElement) error {
 	p := params{"element": element.id}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/touch/longclick", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/touch/longclick", s.Id)
 	return err
 }
 
@@ -940,7 +1432,7 @@ func (s Session) TouchLongClick(element WebElement) error {
 //This flickcommand starts at a particulat screen location.
 func (s Session) TouchFlick(element WebElement, xoffset, yoffset, speed int) error {
 	p := params{"element": element.id, "xoffset": xoffset, "yoffset": yoffset, "speed": speed}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/touch/flick", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/touch/flick", s.Id)
 	return err
 }
 
@@ -948,13 +1440,13 @@ func (s Session) TouchFlick(element WebElement, xoffset, yoffset, speed int) err
 //Use this flick command if you don't care where the flick starts on the screen.
 func (s Session) TouchFlickAnywhere(xspeed, yspeed int) error {
 	p := params{"xspeed": xspeed, "yspeed": yspeed}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/touch/flick", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/touch/flick", s.Id)
 	return err
 }
 
 //Get the current geo location.
 func (s Session) GetGeoLocation() (GeoLocation, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/location", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/location", s.Id)
 	if err != nil {
 		return GeoLocation{}, err
 	}
@@ -966,13 +1458,13 @@ func (s Session) GetGeoLocation() (GeoLocation, error) {
 //Set the current geo location.
 func (s Session) SetGeoLocation(location GeoLocation) error {
 	p := params{"location": location}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/location", s.Id)
+	_, _, err := s.do(p, "POST", "/session/%s/location", s.Id)
 	return err
 }
 
 //helper functions, storageType can be "local_storage" or "session_storage"
 func (s Session) storageGetKeys(storageType string) ([]string, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/%s", s.Id, storageType)
+	_, data, err := s.do(nil, "GET", "/session/%s/%s", s.Id, storageType)
 	if err != nil {
 		return nil, err
 	}
@@ -983,18 +1475,18 @@ func (s Session) storageGetKeys(storageType string) ([]string, error) {
 
 func (s Session) storageSetKey(storageType, key, value string) error {
 	p := params{"key": key, "value": value}
-	_, _, err := s.wd.do(p, "POST", "/session/%s/%s", s.Id, storageType)
+	_, _, err := s.do(p, "POST", "/session/%s/%s", s.Id, storageType)
 	return err
 }
 
 func (s Session) storageClear(storageType string) error {
-	_, _, err := s.wd.do(nil, "DELETE", "/session/%s/%s", s.Id, storageType)
+	_, _, err := s.do(nil, "DELETE", "/session/%s/%s", s.Id, storageType)
 	return err
 }
 
 //TODO protocol specification doesn't specify what is returned, I guess a string
 func (s Session) storageGetKey(storageType, key string) (string, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/%s/key/%s", s.Id, storageType, key)
+	_, data, err := s.do(nil, "GET", "/session/%s/%s/key/%s", s.Id, storageType, key)
 	if err != nil {
 		return "", err
 	}
@@ -1004,13 +1496,13 @@ func (s Session) storageGetKey(storageType, key string) (string, error) {
 }
 
 func (s Session) storageRemoveKey(storageType string, key string) error {
-	_, _, err := s.wd.do(nil, "DELETE", "/session/%s/%s/key/%s", s.Id, storageType, key)
+	_, _, err := s.do(nil, "DELETE", "/session/%s/%s/key/%s", s.Id, storageType, key)
 	return err
 }
 
 //Get the number of items in the storage.
 func (s Session) storageSize(storageType string) (int, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/%s/size", s.Id, storageType)
+	_, data, err := s.do(nil, "GET", "/session/%s/%s/size", s.Id, storageType)
 	if err != nil {
 		return -1, err
 	}
@@ -1082,7 +1574,7 @@ func (s Session) SessionStorageSize() (int, error) {
 //Get the log for a given log type.
 func (s Session) Log(logType string) ([]LogEntry, error) {
 	p := params{"type": logType}
-	_, data, err := s.wd.do(p, "POST", "/session/%s/log", s.Id)
+	_, data, err := s.do(p, "POST", "/session/%s/log", s.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -1093,7 +1585,7 @@ func (s Session) Log(logType string) ([]LogEntry, error) {
 
 //Get available log types.
 func (s Session) LogTypes() ([]string, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/log/types", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/log/types", s.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -1104,7 +1596,7 @@ func (s Session) LogTypes() ([]string, error) {
 
 //Get the status of the html5 application cache.
 func (s Session) GetHTML5CacheStatus() (HTML5CacheStatus, error) {
-	_, data, err := s.wd.do(nil, "GET", "/session/%s/application_cache/status", s.Id)
+	_, data, err := s.do(nil, "GET", "/session/%s/application_cache/status", s.Id)
 	if err != nil {
 		return 0, err
 	}